@@ -6,20 +6,6 @@ import (
 	"time"
 )
 
-type solcConfigSettings struct {
-	Optimizer struct {
-		Enabled bool   `json:"enabled"`
-		Runs    uint64 `json:"runs"`
-	} `json:"optimizer"`
-
-	Metadata struct {
-		BytecodeHash string `json:"bytecodeHash"`
-		AppendCBOR   bool   `json:"appendCBOR"`
-	} `json:"metadata"`
-
-	OutputSelection map[string]interface{} `json:"outputSelection"`
-}
-
 // contractArtifacts is the output file generated for each contract
 type contractArtifact struct {
 	ABI               json.RawMessage   `json:"abi"`
@@ -29,6 +15,14 @@ type contractArtifact struct {
 	RawMetadata       string            `json:"rawMetadata"`
 	Metadata          json.RawMessage   `json:"metadata"`
 	AST               json.RawMessage   `json:"ast"`
+	StorageLayout     *StorageLayout    `json:"storageLayout,omitempty"`
+	GasEstimates      *GasEstimates     `json:"gasEstimates,omitempty"`
+	IR                string            `json:"ir,omitempty"`
+	IROptimized       string            `json:"irOptimized,omitempty"`
+	Assembly          string            `json:"assembly,omitempty"`
+	LegacyAssembly    json.RawMessage   `json:"legacyAssembly,omitempty"`
+	DevDoc            *DevDoc           `json:"devdoc,omitempty"`
+	UserDoc           *UserDoc          `json:"userdoc,omitempty"`
 }
 
 type Source struct {
@@ -38,9 +32,27 @@ type Source struct {
 	// Filename is the name of the file
 	Filename string
 
+	// IncludePath is the include path where the source was found.
+	// It is empty for the sources in the contracts directory.
+	IncludePath string
+
 	// ModTime is the modified time of the source
 	ModTime time.Time
 
+	// Size is the size of the source in bytes
+	Size int64
+
+	// Hash is the keccak256 hash of the content of the source
+	Hash string
+
+	// HashTime is the time the content was read to compute the hash
+	HashTime time.Time
+
+	// Fingerprint identifies the compiler version and settings the component
+	// rooted at this source was last compiled with. It is only set for the
+	// sources that are not imported by any other.
+	Fingerprint string
+
 	// Versions are the required version for this source
 	Version []string
 
@@ -50,11 +62,20 @@ type Source struct {
 	AST json.RawMessage
 }
 
-// relPath returns the relative path of the source inside the contracts directory
+// relPath returns the source unit name, the relative path of the
+// source inside the contracts directory or its include path
 func (s *Source) relPath() string {
 	return filepath.Join(s.Dir, s.Filename)
 }
 
+// invalidate forces the source to be compiled again the next time
+func (s *Source) invalidate() {
+	s.ModTime = time.Time{}
+	s.Hash = ""
+	s.HashTime = time.Time{}
+	s.Fingerprint = ""
+}
+
 type Contract struct {
 	// Name is the name of the contract
 	Name string
@@ -71,10 +92,31 @@ type Contract struct {
 	Metadata string
 
 	MethodIdentifiers map[string]string
+
+	// The following outputs are only set if requested in the output selection
+
+	StorageLayout *StorageLayout
+
+	GasEstimates *GasEstimates
+
+	IR string
+
+	IROptimized string
+
+	Assembly string
+
+	LegacyAssembly json.RawMessage
+
+	DevDoc *DevDoc
+
+	UserDoc *UserDoc
 }
 
 type Bytecode struct {
-	Object         string          `json:"object"`
-	SrcMap         string          `json:"sourceMap"`
-	LinkReferences json.RawMessage `json:"linkReferences"`
+	Object              string                        `json:"object"`
+	Opcodes             string                        `json:"opcodes,omitempty"`
+	SrcMap              string                        `json:"sourceMap"`
+	LinkReferences      LinkReferences                `json:"linkReferences"`
+	ImmutableReferences map[string][]*CodeRange       `json:"immutableReferences,omitempty"`
+	FunctionDebugData   map[string]*FunctionDebugData `json:"functionDebugData,omitempty"`
 }
@@ -1,21 +1,27 @@
 package gosolc
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	version "github.com/hashicorp/go-version"
 	"github.com/umbracle/gosolc/dag"
 )
 
 func (p *Project) findLocalDiff() ([]*FileDiff, error) {
-	files, err := readDir(p.config.ContractsDir)
+	files, err := readDir(p.config.ContractsDir, p.config.IncludePaths...)
 	if err != nil {
 		return nil, err
 	}
@@ -24,33 +30,120 @@ func (p *Project) findLocalDiff() ([]*FileDiff, error) {
 	if err != nil {
 		return nil, err
 	}
-	diffFiles2, err := calcDiff(sources, p.config.ContractsDir, files)
+
+	// the sources from the include paths are only tracked once they are imported
+	for _, src := range sources {
+		if src.IncludePath == "" {
+			continue
+		}
+		file, err := os.Stat(filepath.Join(src.IncludePath, src.relPath()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		files = append(files, &fileRef{
+			root:    src.IncludePath,
+			path:    src.relPath(),
+			modTime: file.ModTime(),
+			size:    file.Size(),
+		})
+	}
+
+	diffFiles2, updated, err := calcDiff(sources, files)
 	if err != nil {
 		return nil, err
 	}
+	if updated {
+		p.statsUpdated = true
+	}
 
-	// parse the files and update the sources
-	for _, diff := range diffFiles2 {
-		file, err := os.Stat(filepath.Join(p.config.ContractsDir, diff.Path))
+	// parse the files and update the sources. The imports that are not part of the
+	// project yet are looked up in the include paths and parsed too.
+	for i := 0; i < len(diffFiles2); i++ {
+		diff := diffFiles2[i]
+		if diff.Type == FileDiffDel {
+			// the deleted sources are removed when compiling
+			continue
+		}
+
+		file, err := os.Stat(filepath.Join(diff.Root, diff.Path))
 		if err != nil {
 			return nil, err
 		}
 
-		source, err := parseSource(string(diff.Content), diff.Path)
+		source, err := parseSource(string(diff.Content), diff.Path, p.remappings)
 		if err != nil {
 			return nil, err
 		}
 
 		source.ModTime = file.ModTime()
+		source.Size = file.Size()
+		source.Hash = keccak256Hex(diff.Content)
+		source.HashTime = diff.readTime
+		if diff.Root != p.config.ContractsDir {
+			source.IncludePath = diff.Root
+		}
 
 		if err := p.UpsertSource(source); err != nil {
 			return nil, err
 		}
+
+		for _, imp := range source.Imports {
+			if p.getSourceByPath(imp) != nil || containsDiff(diffFiles2, imp) {
+				continue
+			}
+			importDiff, err := p.findImport(imp)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve import '%s' of '%s': %v", imp, diff.Path, err)
+			}
+			diffFiles2 = append(diffFiles2, importDiff)
+		}
 	}
 
 	return diffFiles2, nil
 }
 
+// findImport looks up the source unit name path in the include paths
+func (p *Project) findImport(path string) (*FileDiff, error) {
+	for _, root := range p.config.IncludePaths {
+		fullPath := filepath.Join(root, path)
+
+		file, err := os.Stat(fullPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		readTime := time.Now()
+		content, err := ioutil.ReadFile(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		diff := &FileDiff{
+			Path:     path,
+			Root:     root,
+			Type:     FileDiffAdd,
+			Mod:      file.ModTime(),
+			Content:  content,
+			readTime: readTime,
+		}
+		return diff, nil
+	}
+	return nil, fmt.Errorf("file not found in '%s' or the include paths", p.config.ContractsDir)
+}
+
+func containsDiff(diffs []*FileDiff, path string) bool {
+	for _, diff := range diffs {
+		if diff.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
 type fileWriter struct {
 	absPath string
 }
@@ -81,8 +174,36 @@ func (f *fileWriter) Write(path string, content interface{}) error {
 	return nil
 }
 
+// Remove removes the file at path and its parent directory if it is empty
+func (f *fileWriter) Remove(path string) error {
+	fullPath := filepath.Join(f.absPath, path)
+
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if entries, err := os.ReadDir(filepath.Dir(fullPath)); err == nil && len(entries) == 0 {
+		if err := os.Remove(filepath.Dir(fullPath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// artifactPath returns the path of the artifact of the
+// contract with the name <path>:<contract>
+func artifactPath(name string) string {
+	return filepath.Join("out", strings.Replace(name, ":", "/", -1)) + ".json"
+}
+
 // Compile compiles the application
 func (p *Project) Compile() (*CompilationResult, error) {
+	return p.CompileContext(context.Background())
+}
+
+// CompileContext compiles the application and stops if ctx is done. If the
+// compilation fails, the result of the components that finished is returned
+// along with the error and the other ones are compiled again the next time.
+func (p *Project) CompileContext(ctx context.Context) (*CompilationResult, error) {
 	diffFiles, err := p.findLocalDiff()
 	if err != nil {
 		return nil, err
@@ -92,13 +213,31 @@ func (p *Project) Compile() (*CompilationResult, error) {
 		absPath: p.config.ArtifactsDir,
 	}
 
+	// remove the deleted sources along with their contracts and artifacts
+	for _, diffFile := range diffFiles {
+		if diffFile.Type != FileDiffDel {
+			continue
+		}
+		for _, c := range p.removeSource(diffFile.Path) {
+			if err := fileW.Remove(artifactPath(c.Source + ":" + c.Name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	diffSources := []string{}
 	for _, diffFile := range diffFiles {
 		diffSources = append(diffSources, diffFile.Path)
 	}
-	result, err := p.compileImpl(diffSources)
-	if err != nil {
-		return nil, err
+	result, compileErr := p.compileImpl(ctx, diffSources)
+	if result == nil {
+		return nil, compileErr
+	}
+
+	for _, c := range result.removed {
+		if err := fileW.Remove(artifactPath(c.Source + ":" + c.Name)); err != nil {
+			return nil, err
+		}
 	}
 
 	// write artifacts!
@@ -126,14 +265,29 @@ func (p *Project) Compile() (*CompilationResult, error) {
 			Metadata:          json.RawMessage(contract.Metadata),
 			MethodIdentifiers: contract.MethodIdentifiers,
 			AST:               source.AST,
+			StorageLayout:     contract.StorageLayout,
+			GasEstimates:      contract.GasEstimates,
+			IR:                contract.IR,
+			IROptimized:       contract.IROptimized,
+			Assembly:          contract.Assembly,
+			LegacyAssembly:    contract.LegacyAssembly,
+			DevDoc:            contract.DevDoc,
+			UserDoc:           contract.UserDoc,
 		}
 
-		if err := fileW.Write(filepath.Join("out", strings.Replace(name, ":", "/", -1))+".json", artifact); err != nil {
+		if err := fileW.Write(artifactPath(name), artifact); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(diffFiles) != 0 || len(result.Runs) != 0 || p.statsUpdated {
+		if err := p.writeCache(); err != nil {
 			return nil, err
 		}
+		p.statsUpdated = false
 	}
 
-	return result, nil
+	return result, compileErr
 }
 
 type CompilationResult struct {
@@ -142,17 +296,32 @@ type CompilationResult struct {
 
 	// Runs is the list of independent compilation components
 	Runs []*CompilationRun
+
+	// Diagnostics are the warnings and infos reported by the compiler
+	Diagnostics []*Diagnostic
+
+	// ExecutionTime is the time it took to compile all the components
+	ExecutionTime time.Duration
+
+	// removed are the contracts that the recompiled sources no longer define
+	removed []*Contract
 }
 
 type CompilationRun struct {
 	// Paths of the Solidity contracts for this run
 	Components []string
 
+	// Version is the version of the compiler used in this run
+	Version string
+
+	// StartTime is the time the compiler started with this component
+	StartTime time.Time
+
 	// ExecutionTime is the time it took this component to compile
 	ExecutionTime time.Duration
 }
 
-func (p *Project) compileImpl(updatedFiles []string) (*CompilationResult, error) {
+func (p *Project) compileImpl(ctx context.Context, updatedFiles []string) (*CompilationResult, error) {
 	solidityVersion, err := version.NewVersion(p.config.SolidityVersion)
 	if err != nil {
 		return nil, err
@@ -186,116 +355,556 @@ func (p *Project) compileImpl(updatedFiles []string) (*CompilationResult, error)
 	// add edges
 	for _, src := range sourcesMap {
 		for _, dst := range src.Imports {
-			dst, ok := sourcesMap[dst]
+			dstSrc, ok := sourcesMap[dst]
 			if !ok {
-				panic(fmt.Errorf("BUG: elem in DAG not found: %s", dst.relPath()))
+				return nil, fmt.Errorf("import '%s' of '%s' not found", dst, src.relPath())
 			}
 			dd.AddEdge(dag.Edge{
 				Src: src,
-				Dst: dst,
+				Dst: dstSrc,
 			})
 		}
 	}
 
-	// Create an independent component set for each end node of the graph.
-	// Include the node + all their parent nodes. Only recompute the sets in
-	// which at least one node has been modified.
+	settings, err := settingsFingerprint(p.config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sources that have to be compiled again are the modified
+	// ones and all the sources that import them transitively
+	updatedVertex := []dag.Vertex{}
+	for _, src := range diffSources {
+		updatedVertex = append(updatedVertex, src)
+	}
+	dirty := map[*Source]struct{}{}
+	for _, v := range dd.FindAncestors(updatedVertex...) {
+		dirty[v.(*Source)] = struct{}{}
+	}
+
+	// Create an independent component for each root of the graph (a source that
+	// is not imported) with the root and all the sources it imports. A component
+	// is compiled if its root is dirty, which is the case if any of its sources is,
+	// or if it was compiled with another compiler version or settings.
 	rawComponents := dd.FindComponents()
+	rawComponents = append(rawComponents, cycleComponents(dd, rawComponents, sourcesMap)...)
+	sort.Slice(rawComponents, func(i, j int) bool {
+		return rawComponents[i][0].(*Source).relPath() < rawComponents[j][0].(*Source).relPath()
+	})
 
-	components := [][]string{}
+	components := []*component{}
 	for _, comp := range rawComponents {
-		found := false
+		root := comp[0].(*Source)
+
+		subComp := []string{}
 		for _, i := range comp {
-			for _, j := range diffSources {
-				if i == j {
-					found = true
+			subComp = append(subComp, i.(*Source).relPath())
+		}
+
+		v, err := p.componentVersion(ctx, subComp, sourcesMap, solidityVersion)
+		if err != nil {
+			return nil, err
+		}
+		c := &component{
+			roots:       []string{root.relPath()},
+			sources:     subComp,
+			version:     v,
+			fingerprint: keccak256Hex([]byte(settings + v.String())),
+		}
+
+		if _, ok := dirty[root]; ok || root.Fingerprint != c.fingerprint {
+			components = append(components, c)
+		}
+	}
+	components = mergeComponents(components)
+
+	resp := &CompilationResult{
+		Contracts:   []string{},
+		Runs:        []*CompilationRun{},
+		Diagnostics: []*Diagnostic{},
+	}
+
+	// generate the outputs and compile the components in parallel. The
+	// remaining components are cancelled if any of them fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parallelism := p.config.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	if parallelism > len(components) {
+		parallelism = len(components)
+	}
+
+	type componentResult struct {
+		run    *CompilationRun
+		output *StandardOutput
+	}
+	results := make([]*componentResult, len(components))
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+
+	now := time.Now()
+
+	workCh := make(chan int)
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for indx := range workCh {
+				run, output, err := p.compileComponent(ctx, components[indx], sourcesMap)
+				if err != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					errLock.Unlock()
+					continue
 				}
+				results[indx] = &componentResult{run: run, output: output}
 			}
-		}
-		if found {
-			subComp := []string{}
-			for _, i := range comp {
-				subComp = append(subComp, i.(*Source).relPath())
+		}()
+	}
+	for indx := range components {
+		workCh <- indx
+	}
+	close(workCh)
+	wg.Wait()
+
+	// merge the results in order so that the result is deterministic
+	for indx, res := range results {
+		if res != nil {
+			err := p.mergeOutput(resp, res.run, res.output)
+			if err == nil {
+				for _, i := range components[indx].roots {
+					sourcesMap[i].Fingerprint = components[indx].fingerprint
+				}
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
 			}
-			components = append(components, subComp)
+		}
+		// compile the sources that did not finish again the next time
+		for _, i := range components[indx].sources {
+			sourcesMap[i].invalidate()
 		}
 	}
+	resp.ExecutionTime = time.Since(now)
 
-	resp := &CompilationResult{
-		Contracts: []string{},
-		Runs:      []*CompilationRun{},
+	return resp, firstErr
+}
+
+// settingsFingerprint returns a hash of the settings that change the output
+// of the compiler. Along with the compiler version, it identifies how a
+// component was compiled.
+func settingsFingerprint(config *Config) (string, error) {
+	obj := struct {
+		IncludePaths []string
+		Settings     *Settings
+	}{
+		IncludePaths: config.IncludePaths,
+		Settings:     NewStandardInput(nil, config).Settings,
 	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return keccak256Hex(data), nil
+}
 
-	// generate the outputs and compile
+// cycleComponents returns the components of the sources that are not reachable
+// from any root of the graph because they import each other in a cycle. The roots
+// are picked in path order so that they are the same between compilations.
+func cycleComponents(dd *dag.Dag, components [][]dag.Vertex, sourcesMap map[string]*Source) [][]dag.Vertex {
+	covered := map[dag.Vertex]struct{}{}
 	for _, comp := range components {
-		pragmas := []string{}
-		for _, i := range comp {
-			pragmas = append(pragmas, strings.Split(sourcesMap[i].Version[0], " ")...)
+		for _, v := range comp {
+			covered[v] = struct{}{}
 		}
-		pragmas = unique(pragmas)
+	}
 
-		versionConstraint, err := version.NewConstraint(strings.Join(pragmas, ", "))
-		if err != nil {
-			return nil, err
+	paths := []string{}
+	for path, src := range sourcesMap {
+		if _, ok := covered[src]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	res := [][]dag.Vertex{}
+	for _, path := range paths {
+		src := sourcesMap[path]
+		if _, ok := covered[src]; ok {
+			continue
+		}
+		comp := dd.FindDescendants(src)
+
+		// drop the components of the sources imported by this one
+		inComp := map[dag.Vertex]struct{}{}
+		for _, v := range comp {
+			inComp[v] = struct{}{}
+			covered[v] = struct{}{}
+		}
+		n := 0
+		for _, c := range res {
+			if _, ok := inComp[c[0]]; !ok {
+				res[n] = c
+				n++
+			}
+		}
+		res = append(res[:n], comp)
+	}
+	return res
+}
+
+// component is a set of sources that are compiled together
+type component struct {
+	// roots are the names of the roots of the merged components
+	roots []string
+
+	// sources are the names of the sources of the component
+	sources []string
+
+	// version is the version of the compiler for the component
+	version *version.Version
+
+	// fingerprint identifies the compiler version and settings
+	fingerprint string
+}
+
+// mergeComponents merges the components that share sources and are compiled
+// with the same version so that the shared sources are only compiled once
+func mergeComponents(components []*component) []*component {
+	parent := make([]int, len(components))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+		return i
+	}
+
+	for i := range components {
+		for j := i + 1; j < len(components); j++ {
+			if !components[i].version.Equal(components[j].version) {
+				continue
+			}
+			if overlaps(components[i].sources, components[j].sources) {
+				parent[find(j)] = find(i)
+			}
 		}
-		if !versionConstraint.Check(solidityVersion) {
-			panic("not match in solidity compiler")
+	}
+
+	res := []*component{}
+	groups := map[int]*component{}
+	for i, c := range components {
+		g, ok := groups[find(i)]
+		if !ok {
+			g = &component{
+				version:     c.version,
+				fingerprint: c.fingerprint,
+			}
+			groups[find(i)] = g
+			res = append(res, g)
 		}
+		g.roots = append(g.roots, c.roots...)
+		g.sources = unique(append(g.sources, c.sources...))
+	}
+	return res
+}
 
-		input := &solcInput{
-			files:  comp,
-			config: p.config,
+func overlaps(a, b []string) bool {
+	for _, i := range a {
+		for _, j := range b {
+			if i == j {
+				return true
+			}
 		}
+	}
+	return false
+}
+
+// componentVersion returns the compiler version for the sources
+// of a component, the newest one that satisfies all their pragmas
+func (p *Project) componentVersion(ctx context.Context, comp []string, sourcesMap map[string]*Source, preferred *version.Version) (*version.Version, error) {
+	pragmas := map[string]string{}
+	for _, i := range comp {
+		pragmas[i] = sourcesMap[i].Version[0]
+	}
+	versionConstraint, err := pragmasConstraint(pragmas)
+	if err != nil {
+		return nil, err
+	}
 
-		path, err := p.svm.Resolve(solidityVersion.String())
+	v, err := p.resolveVersion(ctx, preferred, versionConstraint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve compiler for %s: %w", strings.Join(comp, ", "), err)
+	}
+	return v, nil
+}
+
+// compileComponent compiles the sources of a component
+func (p *Project) compileComponent(ctx context.Context, c *component, sourcesMap map[string]*Source) (*CompilationRun, *StandardOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	comp := c.sources
+	compiler, err := p.compiler(ctx, c.version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve compiler for %s: %w", strings.Join(comp, ", "), err)
+	}
+
+	contents := map[string]string{}
+	for _, i := range comp {
+		content, err := ioutil.ReadFile(p.sourcePath(sourcesMap[i]))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		contents[i] = string(content)
+	}
+	input := NewStandardInputFromSources(contents, p.config)
+
+	start := time.Now()
+
+	output, err := compiler.Compile(ctx, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkErrors(output); err != nil {
+		return nil, nil, err
+	}
+
+	if output.Errors, err = p.checkDiagnostics(output.Errors); err != nil {
+		return nil, nil, err
+	}
+
+	run := &CompilationRun{
+		Components:    comp,
+		Version:       c.version.String(),
+		StartTime:     start,
+		ExecutionTime: time.Since(start),
+	}
+	return run, output, nil
+}
+
+// mergeOutput updates the contracts and sources of the project
+// with the output of a run and adds it to the result
+func (p *Project) mergeOutput(resp *CompilationResult, run *CompilationRun, output *StandardOutput) error {
+	resp.Diagnostics = appendDiagnostics(resp.Diagnostics, output.Errors...)
+	resp.Runs = append(resp.Runs, run)
+
+	for sourceName, sourceContracts := range output.Contracts {
+		for contractName, contract := range sourceContracts {
+			ctnr := &Contract{
+				Name:              contractName,
+				Source:            sourceName,
+				Abi:               contract.Abi,
+				Bytecode:          contract.EVM.Bytecode,
+				DeployedBytecode:  contract.EVM.DeployedBytecode,
+				Metadata:          contract.Metadata,
+				MethodIdentifiers: contract.EVM.MethodIdentifiers,
+				StorageLayout:     contract.StorageLayout,
+				GasEstimates:      contract.EVM.GasEstimates,
+				IR:                contract.IR,
+				IROptimized:       contract.IROptimized,
+				Assembly:          contract.EVM.Assembly,
+				LegacyAssembly:    contract.EVM.LegacyAssembly,
+				DevDoc:            contract.DevDoc,
+				UserDoc:           contract.UserDoc,
+			}
+			if err := p.UpsertContract(ctnr); err != nil {
+				return err
+			}
+			resp.Contracts = append(resp.Contracts, sourceName+":"+contractName)
+		}
+	}
+
+	for sourceName, source := range output.Sources {
+		src := p.getSourceByPath(sourceName)
+		if src == nil {
+			return fmt.Errorf("source '%s' in the compiler output not found", sourceName)
 		}
+		src.AST = source.AST
+
+		// drop the contracts that are not in the source anymore
+		sourceContracts := output.Contracts[sourceName]
+		resp.removed = append(resp.removed, p.removeContracts(func(c *Contract) bool {
+			_, ok := sourceContracts[c.Name]
+			return c.Source == sourceName && !ok
+		})...)
+	}
+	return nil
+}
+
+// CompileSources compiles the in-memory sources, indexed by source unit name, with
+// the settings of the project. All the imported sources must be included. The
+// sources and the artifacts of the project are not updated.
+func (p *Project) CompileSources(sources map[string]string) (*StandardOutput, error) {
+	return p.CompileSourcesContext(context.Background(), sources)
+}
 
-		now := time.Now()
+// CompileSourcesContext is like CompileSources but the compiler is killed if ctx is done
+func (p *Project) CompileSourcesContext(ctx context.Context, sources map[string]string) (*StandardOutput, error) {
+	solidityVersion, err := version.NewVersion(p.config.SolidityVersion)
+	if err != nil {
+		return nil, err
+	}
 
-		output, err := Compile(path, input)
+	pragmas := map[string]string{}
+	for name, content := range sources {
+		pragma, err := parsePragma(content)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to parse pragma of '%s': %v", name, err)
 		}
+		pragmas[name] = pragma[0]
+	}
+	versionConstraint, err := pragmasConstraint(pragmas)
+	if err != nil {
+		return nil, err
+	}
 
-		resp.Runs = append(resp.Runs, &CompilationRun{
-			Components:    comp,
-			ExecutionTime: time.Since(now),
-		})
+	compiler, _, err := p.resolveCompiler(ctx, solidityVersion, versionConstraint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve compiler: %w", err)
+	}
 
-		for sourceName, sourceContracts := range output.Contracts {
-			for contractName, contract := range sourceContracts {
-				ctnr := &Contract{
-					Name:              contractName,
-					Source:            sourceName,
-					Abi:               contract.Abi,
-					Bytecode:          contract.EVM.Bytecode,
-					DeployedBytecode:  contract.EVM.DeployedBytecode,
-					Metadata:          contract.Metadata,
-					MethodIdentifiers: contract.EVM.MethodIdentifiers,
-				}
-				if err := p.UpsertContract(ctnr); err != nil {
-					return nil, err
-				}
-				resp.Contracts = append(resp.Contracts, sourceName+":"+contractName)
+	output, err := compiler.Compile(ctx, NewStandardInputFromSources(sources, p.config))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkErrors(output); err != nil {
+		return nil, err
+	}
+	if output.Errors, err = p.checkDiagnostics(output.Errors); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// sourcePath returns the path of the source in the filesystem
+func (p *Project) sourcePath(src *Source) string {
+	if src.IncludePath != "" {
+		return filepath.Join(src.IncludePath, src.relPath())
+	}
+	return filepath.Join(p.config.ContractsDir, src.relPath())
+}
+
+// checkDiagnostics removes the ignored diagnostics and fails
+// with the warnings if they are treated as errors
+func (p *Project) checkDiagnostics(diagnostics []*Diagnostic) ([]*Diagnostic, error) {
+	diagnostics = filterDiagnostics(diagnostics, p.config.IgnoredErrorCodes)
+	if p.config.WarningsAsErrors && len(diagnostics) != 0 {
+		warnings := []*Diagnostic{}
+		for _, d := range diagnostics {
+			if d.Severity == SeverityWarning {
+				warnings = append(warnings, d)
 			}
 		}
+		if len(warnings) != 0 {
+			return nil, &DiagnosticsError{Diagnostics: warnings}
+		}
+	}
+	return diagnostics, nil
+}
 
-		for sourceName, source := range output.Sources {
-			src := p.getSourceByPath(sourceName)
-			if src == nil {
-				panic("BUG")
+// appendDiagnostics appends the diagnostics that are not in the list yet. The same
+// diagnostic is reported more than once if a source is compiled in several runs.
+func appendDiagnostics(list []*Diagnostic, diagnostics ...*Diagnostic) []*Diagnostic {
+	for _, d := range diagnostics {
+		found := false
+		for _, dd := range list {
+			if dd.String() == d.String() {
+				found = true
 			}
-			src.AST = source.AST
 		}
+		if !found {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
+// resolveCompiler returns the compiler and its version for a component
+// with the given constraint
+func (p *Project) resolveCompiler(ctx context.Context, preferred *version.Version, constraint version.Constraints) (Compiler, *version.Version, error) {
+	v, err := p.resolveVersion(ctx, preferred, constraint)
+	if err != nil {
+		return nil, nil, err
+	}
+	compiler, err := p.compiler(ctx, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return compiler, v, nil
+}
+
+// resolveVersion returns the compiler version for a component with the given
+// constraint. The configured version is preferred and, in auto version mode, the
+// newest version available in the version manager that satisfies the constraint
+// is used instead.
+func (p *Project) resolveVersion(ctx context.Context, preferred *version.Version, constraint version.Constraints) (*version.Version, error) {
+	if constraint.Check(preferred) {
+		return preferred, nil
+	}
+	if !p.config.AutoVersion {
+		return nil, fmt.Errorf("solidity version %s does not satisfy '%s'", preferred.String(), constraint.String())
+	}
+	_, v, err := p.svm.ResolveConstraintContext(ctx, constraint)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// solcCompiler is the default compiler factory. It runs the solc
+// binaries of the version manager.
+func (p *Project) solcCompiler(ctx context.Context, v *version.Version) (Compiler, error) {
+	path, err := p.svm.ResolveContext(ctx, v.String())
+	if err != nil {
+		return nil, err
+	}
+	compiler := &SolcCompiler{
+		Path:         path,
+		BasePath:     p.config.ContractsDir,
+		IncludePaths: p.config.IncludePaths,
+	}
+	return compiler, nil
+}
+
+// pragmasConstraint returns the version constraint that satisfies
+// all the pragmas, indexed by the name of their source
+func pragmasConstraint(pragmas map[string]string) (version.Constraints, error) {
+	names := []string{}
+	for name := range pragmas {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return resp, nil
+	constraints := []string{}
+	for _, name := range names {
+		res, err := parsePragmaConstraint(pragmas[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pragma of '%s': %v", name, err)
+		}
+		constraints = append(constraints, res...)
+	}
+	return version.NewConstraint(strings.Join(unique(constraints), ", "))
 }
 
 var (
-	importRegexp = regexp.MustCompile(`import (".*"|'.*')`)
+	// matches import "path", import * as x from "path" and import {x} from "path"
+	importRegexp = regexp.MustCompile(`import\s+(?:[^;'"]*?\s*from\s*)?("[^"]*"|'[^']*')`)
 )
 
 func parseDependencies(contract string) []string {
@@ -315,7 +924,7 @@ func parseDependencies(contract string) []string {
 }
 
 var (
-	pragmaRegexp = regexp.MustCompile(`pragma\s+solidity\s+(.*);`)
+	pragmaRegexp = regexp.MustCompile(`pragma\s+solidity\s+([^;]*);`)
 )
 
 func parsePragma(contract string) ([]string, error) {
@@ -326,6 +935,194 @@ func parsePragma(contract string) ([]string, error) {
 	return res[1:], nil
 }
 
+var (
+	pragmaTermRegexp   = regexp.MustCompile(`^(\^|~|>=|<=|>|<|=)?\s*v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?`)
+	pragmaHyphenRegexp = regexp.MustCompile(`^\s+-\s+`)
+)
+
+// pragmaVersion is a version of a pragma term. Only the first n components
+// are set, the rest are missing or wildcards (i.e. 0.8 or 0.8.x).
+type pragmaVersion struct {
+	parts [3]uint64
+	n     int
+}
+
+func (v pragmaVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.parts[0], v.parts[1], v.parts[2])
+}
+
+// next returns the lowest version above all the versions matched by v
+func (v pragmaVersion) next() string {
+	switch v.n {
+	case 1:
+		return fmt.Sprintf("%d.0.0", v.parts[0]+1)
+	case 2:
+		return fmt.Sprintf("%d.%d.0", v.parts[0], v.parts[1]+1)
+	default:
+		return fmt.Sprintf("%d.%d.%d", v.parts[0], v.parts[1], v.parts[2]+1)
+	}
+}
+
+func parsePragmaVersion(parts []string) (pragmaVersion, error) {
+	v := pragmaVersion{}
+	for i, part := range parts {
+		if part == "" || part == "x" || part == "X" || part == "*" {
+			// the components after a wildcard must be wildcards too
+			for _, rest := range parts[i+1:] {
+				if rest != "" && rest != "x" && rest != "X" && rest != "*" {
+					return v, fmt.Errorf("invalid version '%s'", strings.Join(parts, "."))
+				}
+			}
+			break
+		}
+		num, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return v, err
+		}
+		v.parts[i] = num
+		v.n++
+	}
+	return v, nil
+}
+
+// parsePragmaConstraint converts a Solidity version pragma into a list of
+// constraints in the format of hashicorp/go-version. The caret (^) and tilde (~)
+// ranges, the hyphen ranges (a - b) and the partial versions (0.8 or 0.8.x)
+// are expanded into explicit bounds.
+func parsePragmaConstraint(pragma string) ([]string, error) {
+	if strings.Contains(pragma, "||") {
+		return nil, fmt.Errorf("pragma '%s' with '||' is not supported", pragma)
+	}
+
+	rest := strings.TrimSpace(pragma)
+	if rest == "" {
+		return nil, fmt.Errorf("pragma '%s' has no version", pragma)
+	}
+
+	// term parses the next term of the pragma into its operator and version
+	term := func() (string, pragmaVersion, error) {
+		match := pragmaTermRegexp.FindStringSubmatch(rest)
+		if match == nil {
+			return "", pragmaVersion{}, fmt.Errorf("invalid pragma term '%s'", rest)
+		}
+		next := rest[len(match[0]):]
+		if next != "" && !unicode.IsSpace(rune(next[0])) {
+			return "", pragmaVersion{}, fmt.Errorf("invalid pragma term '%s'", rest)
+		}
+		rest = next
+
+		v, err := parsePragmaVersion(match[2:])
+		if err != nil {
+			return "", pragmaVersion{}, err
+		}
+		return match[1], v, nil
+	}
+
+	res := []string{}
+	for rest != "" {
+		op, v, err := term()
+		if err != nil {
+			return nil, err
+		}
+
+		if hyphen := pragmaHyphenRegexp.FindString(rest); hyphen != "" {
+			// hyphen range, the upper bound is inclusive
+			rest = rest[len(hyphen):]
+
+			upperOp, upper, err := term()
+			if err != nil {
+				return nil, err
+			}
+			if op != "" || upperOp != "" {
+				return nil, fmt.Errorf("pragma '%s' has an operator in a hyphen range", pragma)
+			}
+
+			res = append(res, ">= "+v.String())
+			switch upper.n {
+			case 0:
+			case 3:
+				res = append(res, "<= "+upper.String())
+			default:
+				res = append(res, "< "+upper.next())
+			}
+
+			rest = strings.TrimSpace(rest)
+			continue
+		}
+
+		major, minor := v.parts[0], v.parts[1]
+
+		switch op {
+		case "^":
+			// the upper bound increases the first non-zero or last set component
+			switch {
+			case v.n == 0:
+				res = append(res, ">= 0.0.0")
+			case major != 0 || v.n == 1:
+				res = append(res, ">= "+v.String(), fmt.Sprintf("< %d.0.0", major+1))
+			case minor != 0 || v.n == 2:
+				res = append(res, ">= "+v.String(), fmt.Sprintf("< 0.%d.0", minor+1))
+			default:
+				res = append(res, ">= "+v.String(), "< "+v.next())
+			}
+
+		case "~":
+			// the upper bound increases the minor version if specified
+			switch v.n {
+			case 0:
+				res = append(res, ">= 0.0.0")
+			case 1:
+				res = append(res, ">= "+v.String(), fmt.Sprintf("< %d.0.0", major+1))
+			default:
+				res = append(res, ">= "+v.String(), fmt.Sprintf("< %d.%d.0", major, minor+1))
+			}
+
+		case "", "=":
+			// a partial version matches all the versions with that prefix
+			switch v.n {
+			case 0:
+				res = append(res, ">= 0.0.0")
+			case 3:
+				res = append(res, "= "+v.String())
+			default:
+				res = append(res, ">= "+v.String(), "< "+v.next())
+			}
+
+		case ">=":
+			res = append(res, ">= "+v.String())
+
+		case ">":
+			switch v.n {
+			case 0:
+				return nil, fmt.Errorf("pragma '%s' matches no version", pragma)
+			case 3:
+				res = append(res, "> "+v.String())
+			default:
+				res = append(res, ">= "+v.next())
+			}
+
+		case "<":
+			if v.n == 0 {
+				return nil, fmt.Errorf("pragma '%s' matches no version", pragma)
+			}
+			res = append(res, "< "+v.String())
+
+		case "<=":
+			switch v.n {
+			case 0:
+				res = append(res, ">= 0.0.0")
+			case 3:
+				res = append(res, "<= "+v.String())
+			default:
+				res = append(res, "< "+v.next())
+			}
+		}
+
+		rest = strings.TrimSpace(rest)
+	}
+	return res, nil
+}
+
 func unique(a []string) []string {
 	b := []string{}
 	for _, i := range a {
@@ -355,6 +1152,10 @@ type FileDiff struct {
 	// Path of the file being updated
 	Path string
 
+	// Root is the directory the path is relative to, either the
+	// contracts directory or one of the include paths
+	Root string
+
 	// Type of the file update
 	Type FileDiffType
 
@@ -363,10 +1164,26 @@ type FileDiff struct {
 
 	// Content is the content of the file
 	Content []byte
+
+	// readTime is the time the content was read
+	readTime time.Time
 }
 
-func calcDiff(sources []*Source, contractsDir string, files []*fileRef) ([]*FileDiff, error) {
+// racyModTimeWindow is the time after a modification in which the modified
+// time of a file is not trusted since the filesystem might have a coarse
+// resolution and a later edit would keep the same modified time
+const racyModTimeWindow = 2 * time.Second
+
+// calcDiff returns the files that are new, deleted or whose content changed with
+// respect to the sources. The modified time and size are used as a fast pre-check
+// and the content hash decides if a file changed. The pre-check is skipped if the
+// hash was computed within racyModTimeWindow of the modified time since an edit
+// made afterwards might have kept it. The modified time, size and hash time of
+// the sources that were touched but did not change are updated, in which case
+// it returns true.
+func calcDiff(sources []*Source, files []*fileRef) ([]*FileDiff, bool, error) {
 	diff := []*FileDiff{}
+	updated := false
 
 	sourcesMap := map[string]*Source{}
 	for _, src := range sources {
@@ -378,32 +1195,49 @@ func calcDiff(sources []*Source, contractsDir string, files []*fileRef) ([]*File
 		visited[file.path] = struct{}{}
 
 		if src, ok := sourcesMap[file.path]; ok {
-			if !src.ModTime.Equal(file.modTime) {
-				// mod file
-				content, err := ioutil.ReadFile(filepath.Join(contractsDir, file.path))
-				if err != nil {
-					return nil, err
-				}
+			racy := src.HashTime.Before(src.ModTime.Add(racyModTimeWindow))
+			if src.ModTime.Equal(file.modTime) && src.Size == file.size && !racy {
+				continue
+			}
 
-				diff = append(diff, &FileDiff{
-					Path:    file.path,
-					Type:    FileDiffMod,
-					Mod:     file.modTime,
-					Content: content,
-				})
+			readTime := time.Now()
+			content, err := ioutil.ReadFile(filepath.Join(file.root, file.path))
+			if err != nil {
+				return nil, false, err
+			}
+			if src.Hash != "" && src.Hash == keccak256Hex(content) {
+				// touched but not modified
+				src.ModTime = file.modTime
+				src.Size = file.size
+				src.HashTime = readTime
+				updated = true
+				continue
 			}
+
+			// mod file
+			diff = append(diff, &FileDiff{
+				Path:     file.path,
+				Root:     file.root,
+				Type:     FileDiffMod,
+				Mod:      file.modTime,
+				Content:  content,
+				readTime: readTime,
+			})
 		} else {
 			// new file
-			content, err := ioutil.ReadFile(filepath.Join(contractsDir, file.path))
+			readTime := time.Now()
+			content, err := ioutil.ReadFile(filepath.Join(file.root, file.path))
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
 
 			diff = append(diff, &FileDiff{
-				Path:    file.path,
-				Type:    FileDiffAdd,
-				Mod:     file.modTime,
-				Content: content,
+				Path:     file.path,
+				Root:     file.root,
+				Type:     FileDiffAdd,
+				Mod:      file.modTime,
+				Content:  content,
+				readTime: readTime,
 			})
 		}
 	}
@@ -419,10 +1253,12 @@ func calcDiff(sources []*Source, contractsDir string, files []*fileRef) ([]*File
 		}
 	}
 
-	return diff, nil
+	return diff, updated, nil
 }
 
-func parseSource(content, path string) (*Source, error) {
+// parseSource parses the source with the source unit name path. The imports
+// are resolved into source unit names with the relative paths and the remappings.
+func parseSource(content, path string, remappings []*Remapping) (*Source, error) {
 	// new file
 	dir, filename := filepath.Dir(path), filepath.Base(path)
 
@@ -432,6 +1268,9 @@ func parseSource(content, path string) (*Source, error) {
 	if err != nil {
 		return nil, err
 	}
+	for indx, imp := range absImports {
+		absImports[indx] = filepath.Clean(applyRemappings(remappings, path, imp))
+	}
 
 	pragma, err := parsePragma(string(content))
 	if err != nil {
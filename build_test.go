@@ -1,9 +1,19 @@
 package gosolc
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	version "github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/require"
+	"github.com/umbracle/gosolc/svm"
 )
 
 func TestUnique(t *testing.T) {
@@ -44,6 +54,19 @@ func TestParseSource_Dependencies(t *testing.T) {
 				"../Basic.sol",
 			},
 		},
+		{
+			`import {A, B} from "./A.sol"; import * as C from '@c/C.sol';`,
+			[]string{
+				"./A.sol",
+				"@c/C.sol",
+			},
+		},
+		{
+			`import "./A.sol" as A;`,
+			[]string{
+				"./A.sol",
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -63,6 +86,13 @@ func TestParseSource_Pragma(t *testing.T) {
 				">=0.8.0",
 			},
 		},
+		{
+			// the pragma ends at the first semicolon
+			`pragma solidity ^0.8.0; import "./ERC20.sol";`,
+			[]string{
+				"^0.8.0",
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -110,3 +140,524 @@ func TestResolveRelativePaths(t *testing.T) {
 		require.Equal(t, c.res, deps)
 	}
 }
+
+func TestParsePragmaConstraint(t *testing.T) {
+	cases := []struct {
+		pragma      string
+		constraints []string
+	}{
+		{
+			">=0.8.0",
+			[]string{">= 0.8.0"},
+		},
+		{
+			">= 0.8.0 <0.9.0",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		{
+			"0.8.4",
+			[]string{"= 0.8.4"},
+		},
+		{
+			"^0.8.0",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		{
+			"^0.0.3",
+			[]string{">= 0.0.3", "< 0.0.4"},
+		},
+		{
+			"^1.2.3",
+			[]string{">= 1.2.3", "< 2.0.0"},
+		},
+		{
+			"~0.8.1",
+			[]string{">= 0.8.1", "< 0.9.0"},
+		},
+		{
+			"^0.6.0 || ^0.8.0",
+			nil,
+		},
+		// hyphen ranges include the upper bound
+		{
+			"0.8.0 - 0.8.10",
+			[]string{">= 0.8.0", "<= 0.8.10"},
+		},
+		{
+			"0.7 - 0.8",
+			[]string{">= 0.7.0", "< 0.9.0"},
+		},
+		{
+			">=0.7.0 - 0.8.0",
+			nil,
+		},
+		// partial and wildcard versions match any of the missing components
+		{
+			"0.8",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		{
+			"0.8.x",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		{
+			"=0.8.*",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		{
+			"*",
+			[]string{">= 0.0.0"},
+		},
+		{
+			"^0.8",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		{
+			"~0.8.x",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		{
+			">0.7",
+			[]string{">= 0.8.0"},
+		},
+		{
+			"<=0.8.x",
+			[]string{"< 0.9.0"},
+		},
+		{
+			">=0.8.x <0.9",
+			[]string{">= 0.8.0", "< 0.9.0"},
+		},
+		// invalid terms are rejected
+		{
+			"0.x.1",
+			nil,
+		},
+		{
+			"^0.8.0 import",
+			nil,
+		},
+		{
+			"0.8.0abc",
+			nil,
+		},
+		{
+			"",
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		constraints, err := parsePragmaConstraint(c.pragma)
+		if c.constraints == nil {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, c.constraints, constraints)
+
+			_, err = version.NewConstraint(strings.Join(constraints, ", "))
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestProject_ResolveCompiler(t *testing.T) {
+	svmOpt := fakeSVM(t, "0.6.12", "0.8.4", "0.8.10")
+
+	preferred := version.Must(version.NewVersion("0.8.4"))
+
+	cases := []struct {
+		pragma      string
+		autoVersion bool
+		version     string
+	}{
+		// the preferred version is used if it matches
+		{"^0.8.0", false, "0.8.4"},
+		{"^0.8.0", true, "0.8.4"},
+		// the newest matching version is used in auto version mode
+		{"^0.6.0", true, "0.6.12"},
+		{">=0.8.5", true, "0.8.10"},
+		// fail if the preferred version does not match
+		{"^0.6.0", false, ""},
+		// fail if no installed version matches
+		{"^0.7.0", true, ""},
+	}
+
+	for _, c := range cases {
+		p, err := NewProject(WithAutoVersion(c.autoVersion), svmOpt)
+		require.NoError(t, err)
+
+		pragmas, err := parsePragmaConstraint(c.pragma)
+		require.NoError(t, err)
+
+		constraint, err := version.NewConstraint(strings.Join(pragmas, ", "))
+		require.NoError(t, err)
+
+		compiler, v, err := p.resolveCompiler(context.Background(), preferred, constraint)
+		if c.version == "" {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, c.version, v.String())
+			require.Equal(t, "solidity-"+c.version, filepath.Base(compiler.(*SolcCompiler).Path))
+		}
+	}
+}
+
+func TestProject_Imports(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSource(t, dir, "contracts/A.sol", `import "@oz/token/ERC20.sol"; import "@deps/Dep.sol";`)
+	writeSource(t, dir, "contracts/lib/deps/Dep.sol", ``)
+	writeSource(t, dir, "node_modules/@oz/token/ERC20.sol", `import "../utils/Context.sol";`)
+	writeSource(t, dir, "node_modules/@oz/utils/Context.sol", ``)
+	writeSource(t, dir, "node_modules/@oz/Unused.sol", ``)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "contracts", "remappings.txt"), []byte("@deps/=lib/deps/\n"), 0644))
+
+	p, err := NewProject(WithContractsDir(filepath.Join(dir, "contracts")), WithIncludePaths(filepath.Join(dir, "node_modules")))
+	require.NoError(t, err)
+
+	diff, err := p.findLocalDiff()
+	require.NoError(t, err)
+	require.Len(t, diff, 4)
+
+	src := p.getSourceByPath("A.sol")
+	require.Equal(t, []string{"@oz/token/ERC20.sol", "lib/deps/Dep.sol"}, src.Imports)
+	require.Empty(t, src.IncludePath)
+
+	// the imported sources are found in the include path
+	src = p.getSourceByPath("@oz/token/ERC20.sol")
+	require.NotNil(t, src)
+	require.Equal(t, []string{"@oz/utils/Context.sol"}, src.Imports)
+	require.Equal(t, filepath.Join(dir, "node_modules"), src.IncludePath)
+
+	require.NotNil(t, p.getSourceByPath("@oz/utils/Context.sol"))
+	require.Nil(t, p.getSourceByPath("@oz/Unused.sol"))
+
+	// the sources in the include path are tracked for changes
+	diff, err = p.findLocalDiff()
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	writeSource(t, dir, "node_modules/@oz/utils/Context.sol", `contract Context {}`)
+
+	diff, err = p.findLocalDiff()
+	require.NoError(t, err)
+	require.Len(t, diff, 1)
+	require.Equal(t, "@oz/utils/Context.sol", diff[0].Path)
+	require.Equal(t, FileDiffMod, diff[0].Type)
+
+	// fail if an import is not found
+	writeSource(t, dir, "contracts/B.sol", `import "@missing/B.sol";`)
+
+	_, err = p.findLocalDiff()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "@missing/B.sol")
+}
+
+func TestProject_CompileSources(t *testing.T) {
+	// fake compiler that reports its version and prints a canned output
+	output := filepath.Join(filepath.Dir(fakeSolc(t, outputsOutput)), "output.json")
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"--version\" ]; then echo 'Version: 0.8.4+commit.00000000.Linux.g++'; exit 0; fi\n" +
+		"cat > /dev/null\ncat " + output + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "solidity-0.8.4"), []byte(script), 0755))
+
+	p, err := NewProject(WithContractsDir(t.TempDir()), WithSVMOptions(svm.WithDir(dir), svm.WithOffline(true)))
+	require.NoError(t, err)
+
+	res, err := p.CompileSources(map[string]string{
+		"A.sol": "pragma solidity ^0.8.0;\ncontract A {}\n",
+	})
+	require.NoError(t, err)
+	require.Contains(t, res.Contracts["A.sol"], "A")
+
+	// the project is not updated
+	sources, err := p.ListSources()
+	require.NoError(t, err)
+	require.Empty(t, sources)
+
+	// fail if no compiler matches the pragma
+	_, err = p.CompileSources(map[string]string{
+		"A.sol": "pragma solidity ^0.7.0;\ncontract A {}\n",
+	})
+	require.Error(t, err)
+}
+
+func TestCalcDiff_Hash(t *testing.T) {
+	dir := t.TempDir()
+
+	content := []byte("pragma solidity ^0.8.0;\ncontract A {}\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "A.sol"), content, 0644))
+
+	old := time.Now().Add(-time.Hour)
+	src := &Source{
+		Filename: "A.sol",
+		Dir:      ".",
+		ModTime:  old,
+		Size:     int64(len(content)),
+		Hash:     keccak256Hex(content),
+		HashTime: time.Now(),
+	}
+	ref := func(modTime time.Time) []*fileRef {
+		return []*fileRef{{root: dir, path: "A.sol", modTime: modTime, size: int64(len(content))}}
+	}
+
+	// same modified time and size
+	diff, updated, err := calcDiff([]*Source{src}, ref(old))
+	require.NoError(t, err)
+	require.Empty(t, diff)
+	require.False(t, updated)
+
+	// touched but not modified
+	touched := old.Add(time.Minute)
+	diff, updated, err = calcDiff([]*Source{src}, ref(touched))
+	require.NoError(t, err)
+	require.Empty(t, diff)
+	require.True(t, updated)
+	require.Equal(t, touched, src.ModTime)
+
+	// the hash was taken well after the modification
+	require.True(t, src.HashTime.After(touched.Add(racyModTimeWindow)))
+
+	// modified with the same size within the modified time resolution of
+	// the time the hash was taken, even if that was long ago
+	src.ModTime = old
+	src.HashTime = old.Add(time.Second)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "A.sol"), []byte("pragma solidity ^0.8.0;\ncontract B {}\n"), 0644))
+
+	diff, _, err = calcDiff([]*Source{src}, ref(old))
+	require.NoError(t, err)
+	require.Len(t, diff, 1)
+	require.Equal(t, FileDiffMod, diff[0].Type)
+}
+
+func TestProject_Fingerprint(t *testing.T) {
+	svmOpt := fakeSVM(t, "0.6.12", "0.8.4", "0.8.10")
+
+	dir := t.TempDir()
+	writeSource(t, dir, "A.sol", `contract A {}`)
+	writeSource(t, dir, "B.sol", `pragma solidity ^0.6.0; contract B {}`)
+
+	compiler := &fakeCompiler{}
+
+	p, err := NewProject(
+		WithContractsDir(dir),
+		WithCompiler(compiler.factory),
+		WithAutoVersion(true),
+		svmOpt,
+	)
+	require.NoError(t, err)
+
+	versions := func(res *CompilationResult) map[string]string {
+		vs := map[string]string{}
+		for _, run := range res.Runs {
+			vs[strings.Join(run.Components, ",")] = run.Version
+		}
+		return vs
+	}
+
+	res, err := p.Compile()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"A.sol": "0.8.4", "B.sol": "0.6.12"}, versions(res))
+
+	res, err = p.Compile()
+	require.NoError(t, err)
+	require.Empty(t, res.Runs)
+
+	// only the components that resolve to another version are compiled again
+	p.config.SolidityVersion = "0.8.10"
+
+	res, err = p.Compile()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"A.sol": "0.8.10"}, versions(res))
+
+	// all the components are compiled again if the settings change
+	p.config.Runs = 100
+
+	res, err = p.Compile()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"A.sol": "0.8.10", "B.sol": "0.6.12"}, versions(res))
+
+	res, err = p.Compile()
+	require.NoError(t, err)
+	require.Empty(t, res.Runs)
+}
+
+func TestProject_DeletedFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSource(t, dir, "A.sol", `import "./B.sol"; contract A {}`)
+	writeSource(t, dir, "B.sol", `contract B {}`)
+	writeSource(t, dir, "C.sol", `contract C {}`)
+
+	compiler := &fakeCompiler{}
+
+	p, err := NewProject(WithContractsDir(dir), WithCompiler(compiler.factory))
+	require.NoError(t, err)
+
+	_, err = p.Compile()
+	require.NoError(t, err)
+
+	// the source, its contracts and artifacts are removed
+	require.NoError(t, os.Remove(filepath.Join(dir, "C.sol")))
+
+	res, err := p.Compile()
+	require.NoError(t, err)
+	require.Empty(t, res.Runs)
+
+	require.Nil(t, p.getSourceByPath("C.sol"))
+	require.Nil(t, p.findContractByFullName("C.sol:C"))
+
+	_, err = os.Stat(filepath.Join(dir, "out", "C.sol"))
+	require.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(filepath.Join(dir, "out", "A.sol", "A.json"))
+	require.NoError(t, err)
+
+	// the contracts that are not in a recompiled source anymore are removed
+	writeSource(t, dir, "A.sol", `import "./B.sol"; contract A2 {}`)
+
+	res, err = p.Compile()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"A.sol:A2", "B.sol:B"}, res.Contracts)
+
+	require.Nil(t, p.findContractByFullName("A.sol:A"))
+	require.NotNil(t, p.findContractByFullName("A.sol:A2"))
+
+	_, err = os.Stat(filepath.Join(dir, "out", "A.sol", "A.json"))
+	require.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(filepath.Join(dir, "out", "A.sol", "A2.json"))
+	require.NoError(t, err)
+
+	writeSource(t, dir, "A.sol", `import "./B.sol"; contract A {}`)
+
+	_, err = p.Compile()
+	require.NoError(t, err)
+
+	// the sources that import a deleted source fail
+	require.NoError(t, os.Remove(filepath.Join(dir, "B.sol")))
+
+	_, err = p.Compile()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "import 'B.sol' of 'A.sol' not found")
+
+	_, err = p.Compile()
+	require.Error(t, err)
+
+	// and they are compiled again once the source is restored
+	writeSource(t, dir, "B.sol", `contract B {}`)
+
+	res, err = p.Compile()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"A.sol:A", "B.sol:B"}, res.Contracts)
+}
+
+func TestProject_Invalidation(t *testing.T) {
+	svmOpt := fakeSVM(t, "0.8.4", "0.8.10")
+
+	dir := t.TempDir()
+
+	// A imports B and C that both import D (diamond)
+	writeSource(t, dir, "A.sol", `import "./B.sol"; import "./C.sol"; contract A {}`)
+	writeSource(t, dir, "B.sol", `import "./D.sol"; contract B {}`)
+	writeSource(t, dir, "C.sol", `import "./D.sol"; contract C {}`)
+	writeSource(t, dir, "D.sol", `contract D {}`)
+	// E and F are two roots that import G
+	writeSource(t, dir, "E.sol", `import "./G.sol"; contract E {}`)
+	writeSource(t, dir, "F.sol", `import "./G.sol"; contract F {}`)
+	writeSource(t, dir, "G.sol", `contract G {}`)
+	// H and I import J but they require different compiler versions
+	writeSource(t, dir, "H.sol", `import "./J.sol"; contract H {}`)
+	writeSource(t, dir, "I.sol", `pragma solidity <0.8.5; import "./J.sol"; contract I {}`)
+	writeSource(t, dir, "J.sol", `contract J {}`)
+	// K and L import each other and there is no root
+	writeSource(t, dir, "K.sol", `import "./L.sol"; contract K {}`)
+	writeSource(t, dir, "L.sol", `import "./K.sol"; import "./M.sol"; contract L {}`)
+	writeSource(t, dir, "M.sol", `contract M {}`)
+
+	compiler := &fakeCompiler{}
+
+	p, err := NewProject(
+		WithContractsDir(dir),
+		WithCompiler(compiler.factory),
+		WithSolidityVersion("0.8.10"),
+		WithAutoVersion(true),
+		svmOpt,
+	)
+	require.NoError(t, err)
+
+	runs := func() []string {
+		res, err := p.Compile()
+		require.NoError(t, err)
+
+		runs := []string{}
+		for _, run := range res.Runs {
+			comp := append([]string{}, run.Components...)
+			sort.Strings(comp)
+			runs = append(runs, strings.Join(comp, ",")+"@"+run.Version)
+		}
+		return runs
+	}
+
+	// the shared sources are compiled once per compiler version
+	require.Equal(t, []string{
+		"A.sol,B.sol,C.sol,D.sol@0.8.10",
+		"E.sol,F.sol,G.sol@0.8.10",
+		"H.sol,J.sol@0.8.10",
+		"I.sol,J.sol@0.8.4",
+		"K.sol,L.sol,M.sol@0.8.10",
+	}, runs())
+
+	// nothing changed
+	require.Empty(t, runs())
+
+	// the sources that import the modified one transitively are compiled
+	writeSource(t, dir, "D.sol", `contract D { uint256 a; }`)
+	require.Equal(t, []string{"A.sol,B.sol,C.sol,D.sol@0.8.10"}, runs())
+
+	writeSource(t, dir, "B.sol", `import "./D.sol"; contract B { uint256 a; }`)
+	require.Equal(t, []string{"A.sol,B.sol,C.sol,D.sol@0.8.10"}, runs())
+
+	// the components that share the modified source are merged
+	writeSource(t, dir, "G.sol", `contract G { uint256 a; }`)
+	require.Equal(t, []string{"E.sol,F.sol,G.sol@0.8.10"}, runs())
+
+	// the component of an unmodified root is not compiled
+	writeSource(t, dir, "E.sol", `import "./G.sol"; contract E { uint256 a; }`)
+	require.Equal(t, []string{"E.sol,G.sol@0.8.10"}, runs())
+
+	// unless it requires another version
+	writeSource(t, dir, "J.sol", `contract J { uint256 a; }`)
+	require.Equal(t, []string{"H.sol,J.sol@0.8.10", "I.sol,J.sol@0.8.4"}, runs())
+
+	// the sources in a cycle are compiled too
+	writeSource(t, dir, "M.sol", `contract M { uint256 a; }`)
+	require.Equal(t, []string{"K.sol,L.sol,M.sol@0.8.10"}, runs())
+
+	require.Empty(t, runs())
+}
+
+func TestProject_CompileContextDownload(t *testing.T) {
+	// the release list never arrives
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeSource(t, dir, "A.sol", `contract A {}`)
+
+	p, err := NewProject(WithContractsDir(dir), WithSVMOptions(svm.WithDir(t.TempDir()), svm.WithBaseURL(srv.URL), svm.WithManifestURL(srv.URL)))
+	require.NoError(t, err)
+
+	// the download of the compiler is aborted
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = p.CompileContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,115 @@
+package gosolc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// cacheFormat is the version of the cache file. The
+	// cache is discarded if it was written with another format.
+	cacheFormat = "gosolc-3"
+
+	// cacheFile is the path of the cache inside the artifacts directory
+	cacheFile = "cache/gosolc.json"
+)
+
+// buildCache is the state of the project that is persisted between
+// compilations so that only the modified sources are compiled again
+type buildCache struct {
+	Format string `json:"format"`
+
+	// Remappings and IncludePaths are the ones used to resolve the imports of the sources
+	Remappings   []string `json:"remappings"`
+	IncludePaths []string `json:"includePaths"`
+
+	Sources   []*Source   `json:"sources"`
+	Contracts []*Contract `json:"contracts"`
+}
+
+func (p *Project) cachePath() string {
+	return filepath.Join(p.config.ArtifactsDir, cacheFile)
+}
+
+// loadCache restores the sources and contracts of the cache file. The cache
+// is ignored if it does not exist or it is invalid. The sources compiled
+// with other settings are compiled again since their fingerprint changes.
+// If the remappings or the include paths changed, the sources are parsed
+// again since their imports might resolve to other files.
+func (p *Project) loadCache() error {
+	data, err := ioutil.ReadFile(p.cachePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var cache *buildCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil
+	}
+	if cache == nil || cache.Format != cacheFormat {
+		return nil
+	}
+
+	p.sources = cache.Sources
+	p.contracts = cache.Contracts
+
+	if !stringsEqual(cache.Remappings, p.config.Remappings) || !stringsEqual(cache.IncludePaths, p.config.IncludePaths) {
+		for _, src := range p.sources {
+			src.invalidate()
+		}
+	}
+	return nil
+}
+
+// writeCache writes the sources and contracts of the project to the cache file
+func (p *Project) writeCache() error {
+	cache := &buildCache{
+		Format:       cacheFormat,
+		Remappings:   p.config.Remappings,
+		IncludePaths: p.config.IncludePaths,
+		Sources:      p.sources,
+		Contracts:    p.contracts,
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	path := p.cachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that the cache is never left half written
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".gosolc-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package gosolc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProject_Cache(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSource(t, dir, "A.sol", `import "./B.sol"; contract A {}`)
+	writeSource(t, dir, "B.sol", `contract B {}`)
+	writeSource(t, dir, "C.sol", `contract C {}`)
+
+	compile := func(opts ...Option) *CompilationResult {
+		compiler := &fakeCompiler{}
+
+		p, err := NewProject(append([]Option{WithContractsDir(dir), WithCompiler(compiler.factory)}, opts...)...)
+		require.NoError(t, err)
+
+		res, err := p.Compile()
+		require.NoError(t, err)
+
+		// the contracts of the previous compilations are restored
+		contracts, err := p.ListContracts()
+		require.NoError(t, err)
+		require.Len(t, contracts, 3)
+
+		return res
+	}
+
+	res := compile()
+	require.Len(t, res.Contracts, 3)
+
+	_, err := os.Stat(filepath.Join(dir, cacheFile))
+	require.NoError(t, err)
+
+	// nothing changed
+	res = compile()
+	require.Empty(t, res.Runs)
+
+	// touching a file does not compile it again
+	touched := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "C.sol"), touched, touched))
+
+	res = compile()
+	require.Empty(t, res.Runs)
+
+	// but its modified time is saved so that it is not read again
+	p, err := NewProject(WithContractsDir(dir))
+	require.NoError(t, err)
+	require.True(t, p.getSourceByPath("C.sol").ModTime.Equal(touched))
+
+	// only the modified component is compiled
+	writeSource(t, dir, "C.sol", `contract C { uint256 a; }`)
+
+	res = compile()
+	require.Equal(t, []string{"C.sol:C"}, res.Contracts)
+
+	res = compile()
+	require.Empty(t, res.Runs)
+
+	// all the components are compiled again if the settings change
+	res = compile(WithRuns(100))
+	require.Len(t, res.Contracts, 3)
+
+	res = compile(WithRuns(100))
+	require.Empty(t, res.Runs)
+
+	// or if the cache is invalid
+	require.NoError(t, os.WriteFile(filepath.Join(dir, cacheFile), []byte("{"), 0644))
+
+	res = compile(WithRuns(100))
+	require.Len(t, res.Contracts, 3)
+}
+
+func TestProject_CacheRemappings(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSource(t, dir, "A.sol", `import "@deps/Dep.sol"; contract A {}`)
+	writeSource(t, dir, "lib/deps/Dep.sol", `contract Dep {}`)
+	writeSource(t, dir, "vendor/deps/Dep.sol", `contract Dep {}`)
+
+	compile := func(remapping string) [][]string {
+		compiler := &fakeCompiler{}
+
+		p, err := NewProject(WithContractsDir(dir), WithCompiler(compiler.factory), WithRemappings(remapping), WithParallelism(1))
+		require.NoError(t, err)
+
+		_, err = p.Compile()
+		require.NoError(t, err)
+
+		return compiler.compiled()
+	}
+
+	require.Equal(t, [][]string{
+		{"A.sol", "lib/deps/Dep.sol"},
+		{"vendor/deps/Dep.sol"},
+	}, compile("@deps/=lib/deps/"))
+
+	// the imports are resolved again with the new remappings
+	require.Equal(t, [][]string{
+		{"A.sol", "vendor/deps/Dep.sol"},
+		{"lib/deps/Dep.sol"},
+	}, compile("@deps/=vendor/deps/"))
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/umbracle/gosolc"
@@ -27,11 +29,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	res, err := p.Compile()
+	// stop the compilation on ctrl-c
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	res, err := p.CompileContext(ctx)
 	if err != nil {
 		fmt.Printf("[ERROR]: Failed to compile: %v", err)
 		os.Exit(1)
 	}
 
+	for _, d := range res.Diagnostics {
+		fmt.Printf("[%s]: %s\n", strings.ToUpper(string(d.Severity)), d.String())
+	}
 	fmt.Printf("[RESULT]: Compiled contracts: %s", strings.Join(res.Contracts, ","))
 }
@@ -2,138 +2,187 @@ package gosolc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
-	"github.com/hashicorp/go-multierror"
+	version "github.com/hashicorp/go-version"
 )
 
-type Optimizer struct {
-	Enabled bool
-	Runs    int
-}
-
-type solcInput struct {
-	files  []string
-	config *Config
-}
-
 type Artifact struct {
 	Abi json.RawMessage `json:"abi"`
 
 	EVM struct {
 		Bytecode          *Bytecode         `json:"bytecode"`
 		DeployedBytecode  *Bytecode         `json:"deployedBytecode"`
-		Opcodes           string            `json:"opcodes"`
-		SourceMap         string            `json:"sourceMap"`
 		MethodIdentifiers map[string]string `json:"methodIdentifiers"`
+		GasEstimates      *GasEstimates     `json:"gasEstimates"`
+		Assembly          string            `json:"assembly"`
+		LegacyAssembly    json.RawMessage   `json:"legacyAssembly"`
 	} `json:"evm"`
 
-	Metadata string `json:"metadata"`
+	Metadata      string         `json:"metadata"`
+	StorageLayout *StorageLayout `json:"storageLayout"`
+	IR            string         `json:"ir"`
+	IROptimized   string         `json:"irOptimized"`
+	DevDoc        *DevDoc        `json:"devdoc"`
+	UserDoc       *UserDoc       `json:"userdoc"`
 }
 
-type solcOutput struct {
-	Errors    []*solcError
-	Contracts map[string]map[string]*Artifact
-	Sources   map[string]*solcSourceFile
-	Version   string
+// StandardOutput is the standard JSON output of the compiler
+type StandardOutput struct {
+	Errors    []*Diagnostic                   `json:"errors"`
+	Contracts map[string]map[string]*Artifact `json:"contracts"`
+	Sources   map[string]*OutputSource        `json:"sources"`
+	Version   string                          `json:"version"`
 }
 
-type solcError struct {
-	FormattedMessage string `json:"formattedMessage"`
+// OutputSource is the output of a source unit
+type OutputSource struct {
+	ID  int             `json:"id"`
+	AST json.RawMessage `json:"ast"`
 }
 
-type solcSourceFile struct {
-	AST json.RawMessage
+// Compiler compiles a standard JSON input. The compilation errors
+// are reported in the diagnostics of the output.
+type Compiler interface {
+	Compile(ctx context.Context, input *StandardInput) (*StandardOutput, error)
 }
 
-var inputDesc = `{
-	"language": "Solidity",
-	"sources": {
-		{{range $index, $element := .Files}}
-		{{if $index}},{{end}}
-		"{{ $element }}": {
-			"urls": [
-				"{{ $element }}"
-			]
-		}
-		{{end}}
-	},
-	"settings": {
-		"optimizer": {
-			"enabled": {{.Enabled}},
-			"runs": {{.Runs}}
-		},
-		"outputSelection": {
-			"*": {
-				"": [
-					"ast"
-				],
-				"*": [
-					"abi",
-					"evm.bytecode",
-					"evm.deployedBytecode",
-					"evm.methodIdentifiers",
-					"metadata"
-				]
-			}
-		}
-	}
-}`
+// CompilerFactory returns the compiler for the given solc version. The
+// context is done if the compilation is aborted (i.e. while downloading).
+type CompilerFactory func(ctx context.Context, v *version.Version) (Compiler, error)
 
-func Compile(path string, input *solcInput) (*solcOutput, error) {
-	tmplInput := map[string]interface{}{
-		"Files":   input.files,
-		"Runs":    input.config.Runs,
-		"Enabled": input.config.Runs != 0,
-	}
-	tmpl, err := template.New("input").Parse(inputDesc)
-	if err != nil {
-		return nil, err
-	}
+// SolcCompiler runs a solc binary with the standard JSON input
+type SolcCompiler struct {
+	// Path is the path of the solc binary
+	Path string
 
-	var tpl bytes.Buffer
-	if err := tmpl.Execute(&tpl, tmplInput); err != nil {
-		return nil, err
-	}
+	// BasePath is the directory the sources are resolved from. The compiler
+	// does not read from the filesystem if it is empty.
+	BasePath string
 
-	absPath, err := filepath.Abs(input.config.ContractsDir)
-	if err != nil {
+	// IncludePaths are the other directories the sources are resolved from
+	IncludePaths []string
+}
+
+func (s *SolcCompiler) Compile(ctx context.Context, input *StandardInput) (*StandardOutput, error) {
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(input); err != nil {
 		return nil, err
 	}
 
 	args := []string{
 		"--standard-json",
-		"--base-path", absPath,
-		"--allow-paths", absPath,
+	}
+
+	if s.BasePath != "" {
+		absPath, err := filepath.Abs(s.BasePath)
+		if err != nil {
+			return nil, err
+		}
+
+		allowPaths := []string{absPath}
+		args = append(args, "--base-path", absPath)
+		for _, includePath := range s.IncludePaths {
+			absIncludePath, err := filepath.Abs(includePath)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--include-path", absIncludePath)
+			allowPaths = append(allowPaths, absIncludePath)
+		}
+		args = append(args, "--allow-paths", strings.Join(allowPaths, ","))
 	}
 
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command(path, args...)
+	cmd := exec.CommandContext(ctx, s.Path, args...)
 
-	cmd.Stdin = bytes.NewBuffer(tpl.Bytes())
+	cmd.Stdin = &data
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	// the context only kills the solc process, kill its whole process
+	// group instead so that no child keeps the output open
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	err := cmd.Wait()
+	close(done)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("failed to compile: %s", stderr.String())
 	}
 
-	var output *solcOutput
+	var output *StandardOutput
 	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
 		return nil, err
 	}
+	return output, nil
+}
 
-	var outputErr error
-	if len(output.Errors) != 0 {
-		for _, err := range output.Errors {
-			outputErr = multierror.Append(outputErr, fmt.Errorf(err.FormattedMessage))
-		}
-		return nil, outputErr
-	}
+// Compile runs the solc binary at path with the standard JSON input. The
+// sources are resolved with respect to the basePath directory and the
+// includePaths. It fails with a DiagnosticsError if the compiler reports any error.
+func Compile(path string, basePath string, input *StandardInput, includePaths ...string) (*StandardOutput, error) {
+	return CompileContext(context.Background(), path, basePath, input, includePaths...)
+}
 
+// CompileContext is like Compile but the compiler is killed if ctx is done
+func CompileContext(ctx context.Context, path string, basePath string, input *StandardInput, includePaths ...string) (*StandardOutput, error) {
+	compiler := &SolcCompiler{
+		Path:         path,
+		BasePath:     basePath,
+		IncludePaths: includePaths,
+	}
+	output, err := compiler.Compile(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkErrors(output); err != nil {
+		return nil, err
+	}
 	return output, nil
 }
+
+// CompileSources runs the solc binary at path with the in-memory sources, indexed
+// by source unit name, and the given configuration. All the imported sources
+// must be included since the compiler does not read from the filesystem.
+func CompileSources(path string, sources map[string]string, config *Config) (*StandardOutput, error) {
+	return Compile(path, "", NewStandardInputFromSources(sources, config))
+}
+
+// checkErrors fails with a DiagnosticsError if there is any error in the
+// output, the other diagnostics are returned in the output
+func checkErrors(output *StandardOutput) error {
+	errs := []*Diagnostic{}
+	for _, d := range output.Errors {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	if len(errs) != 0 {
+		return &DiagnosticsError{Diagnostics: errs}
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package gosolc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/umbracle/gosolc/svm"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSolc writes a compiler script that prints the given standard JSON output.
+// The script stores its input and arguments in the input.json and args files.
+func fakeSolc(t *testing.T, output string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output.json"), []byte(output), 0644))
+
+	path := filepath.Join(dir, "solc")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+		"cat > " + filepath.Join(dir, "input.json") + "\n" +
+		"cat " + filepath.Join(dir, "output.json") + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+
+	return path
+}
+
+const outputsOutput = `{
+	"contracts": {
+		"A.sol": {
+			"A": {
+				"abi": [],
+				"evm": {
+					"bytecode": {
+						"object": "6080",
+						"opcodes": "PUSH1 0x80",
+						"sourceMap": "0:1:0:-:0",
+						"linkReferences": {},
+						"functionDebugData": {
+							"@_12": {
+								"entryPoint": null,
+								"id": 12,
+								"parameterSlots": 0,
+								"returnSlots": 0
+							}
+						}
+					},
+					"deployedBytecode": {
+						"object": "6080",
+						"immutableReferences": {
+							"3": [
+								{
+									"start": 10,
+									"length": 32
+								}
+							]
+						}
+					},
+					"gasEstimates": {
+						"creation": {
+							"codeDepositCost": "1000",
+							"executionCost": "infinite",
+							"totalCost": "infinite"
+						},
+						"external": {
+							"a()": "2000"
+						}
+					},
+					"assembly": "/* assembly */"
+				},
+				"storageLayout": {
+					"storage": [
+						{
+							"astId": 3,
+							"contract": "A.sol:A",
+							"label": "a",
+							"offset": 0,
+							"slot": "0",
+							"type": "t_uint256"
+						}
+					],
+					"types": {
+						"t_uint256": {
+							"encoding": "inplace",
+							"label": "uint256",
+							"numberOfBytes": "32"
+						}
+					}
+				},
+				"ir": "object \"A\" {}",
+				"devdoc": {
+					"kind": "dev",
+					"version": 1,
+					"title": "A contract",
+					"methods": {
+						"a()": {
+							"details": "returns a",
+							"returns": {
+								"_0": "the value"
+							}
+						}
+					}
+				},
+				"userdoc": {
+					"kind": "user",
+					"version": 1,
+					"notice": "Stores a value"
+				}
+			}
+		}
+	}
+}`
+
+func TestCompile_Outputs(t *testing.T) {
+	cfg := DefaultConfig()
+	WithOutputSelection(OutputStorageLayout, OutputGasEstimates, OutputIR, OutputDevDoc, OutputUserDoc, OutputAssembly)(cfg)
+
+	input := NewStandardInput([]string{"A.sol"}, cfg)
+	require.Equal(t, []string{
+		"abi",
+		"evm.bytecode",
+		"evm.deployedBytecode",
+		"evm.methodIdentifiers",
+		"metadata",
+		"storageLayout",
+		"evm.gasEstimates",
+		"ir",
+		"devdoc",
+		"userdoc",
+		"evm.assembly",
+	}, input.Settings.OutputSelection["*"]["*"])
+
+	output, err := Compile(fakeSolc(t, outputsOutput), ".", input)
+	require.NoError(t, err)
+
+	a := output.Contracts["A.sol"]["A"]
+
+	require.Equal(t, "PUSH1 0x80", a.EVM.Bytecode.Opcodes)
+	require.Equal(t, 12, *a.EVM.Bytecode.FunctionDebugData["@_12"].ID)
+	require.Nil(t, a.EVM.Bytecode.FunctionDebugData["@_12"].EntryPoint)
+	require.Equal(t, []*CodeRange{{Start: 10, Length: 32}}, a.EVM.DeployedBytecode.ImmutableReferences["3"])
+
+	require.Equal(t, "infinite", a.EVM.GasEstimates.Creation["totalCost"])
+	require.Equal(t, "2000", a.EVM.GasEstimates.External["a()"])
+	require.Equal(t, "/* assembly */", a.EVM.Assembly)
+
+	require.Equal(t, "a", a.StorageLayout.Storage[0].Label)
+	require.Equal(t, "32", a.StorageLayout.Types["t_uint256"].NumberOfBytes)
+
+	require.Equal(t, `object "A" {}`, a.IR)
+	require.Equal(t, "A contract", a.DevDoc.Title)
+	require.Equal(t, "the value", a.DevDoc.Methods["a()"].Returns["_0"])
+	require.Equal(t, "Stores a value", a.UserDoc.Notice)
+}
+
+func TestCompileSources(t *testing.T) {
+	path := fakeSolc(t, outputsOutput)
+
+	content := "pragma solidity ^0.8.0;\ncontract A {}\n"
+	output, err := CompileSources(path, map[string]string{"A.sol": content}, DefaultConfig())
+	require.NoError(t, err)
+	require.Contains(t, output.Contracts["A.sol"], "A")
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(path), "input.json"))
+	require.NoError(t, err)
+
+	var input *StandardInput
+	require.NoError(t, json.Unmarshal(data, &input))
+	require.Equal(t, content, input.Sources["A.sol"].Content)
+	require.Equal(t, keccak256Hex([]byte(content)), input.Sources["A.sol"].Keccak256)
+	require.Empty(t, input.Sources["A.sol"].URLs)
+
+	// the compiler does not read the filesystem
+	args, err := os.ReadFile(filepath.Join(filepath.Dir(path), "args"))
+	require.NoError(t, err)
+	require.Equal(t, "--standard-json\n", string(args))
+}
+
+// writeSource writes the source at path inside dir. The source
+// requires ^0.8.0 unless the content has its own pragma.
+func writeSource(t *testing.T, dir, path, content string) {
+	t.Helper()
+
+	if !strings.HasPrefix(content, "pragma") {
+		content = "pragma solidity ^0.8.0;\n" + content
+	}
+	path = filepath.Join(dir, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// fakeSVM installs fake compilers that only report their version and
+// returns the option to use them with an offline version manager
+func fakeSVM(t *testing.T, versions ...string) Option {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, v := range versions {
+		script := "#!/bin/sh\necho 'Version: " + v + "+commit.00000000.Linux.g++'\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "solidity-"+v), []byte(script), 0755))
+	}
+	return WithSVMOptions(svm.WithDir(dir), svm.WithOffline(true))
+}
+
+// fakeCompiler is a compiler that outputs the contracts defined in each source
+// of the input or one named after the file (i.e. A for contracts/A.sol)
+type fakeCompiler struct {
+	lock     sync.Mutex
+	inputs   []*StandardInput
+	versions []string
+
+	// hook is called before each compilation and fails it if it returns an error
+	hook func(ctx context.Context, input *StandardInput) error
+}
+
+func (f *fakeCompiler) factory(ctx context.Context, v *version.Version) (Compiler, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.versions = append(f.versions, v.String())
+	return f, nil
+}
+
+var fakeContractRegexp = regexp.MustCompile(`contract\s+(\w+)`)
+
+func (f *fakeCompiler) Compile(ctx context.Context, input *StandardInput) (*StandardOutput, error) {
+	if f.hook != nil {
+		if err := f.hook(ctx, input); err != nil {
+			return nil, err
+		}
+	}
+
+	f.lock.Lock()
+	f.inputs = append(f.inputs, input)
+	f.lock.Unlock()
+
+	output := &StandardOutput{
+		Contracts: map[string]map[string]*Artifact{},
+		Sources:   map[string]*OutputSource{},
+	}
+	for name, src := range input.Sources {
+		contracts := []string{}
+		for _, match := range fakeContractRegexp.FindAllStringSubmatch(src.Content, -1) {
+			contracts = append(contracts, match[1])
+		}
+		if len(contracts) == 0 {
+			contracts = append(contracts, strings.TrimSuffix(filepath.Base(name), ".sol"))
+		}
+
+		output.Contracts[name] = map[string]*Artifact{}
+		for _, contract := range contracts {
+			artifact := &Artifact{
+				Abi:      json.RawMessage(`[]`),
+				Metadata: `{}`,
+			}
+			artifact.EVM.Bytecode = &Bytecode{Object: "6080"}
+			artifact.EVM.DeployedBytecode = &Bytecode{Object: "6080"}
+
+			output.Contracts[name][contract] = artifact
+		}
+		output.Sources[name] = &OutputSource{
+			AST: json.RawMessage(`{}`),
+		}
+	}
+	return output, nil
+}
+
+// compiled returns the sources compiled in each run sorted by name
+func (f *fakeCompiler) compiled() [][]string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	res := [][]string{}
+	for _, input := range f.inputs {
+		names := []string{}
+		for name := range input.Sources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		res = append(res, names)
+	}
+	return res
+}
+
+func TestProject_Compiler(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSource(t, dir, "A.sol", `import "./B.sol"; contract A {}`)
+	writeSource(t, dir, "B.sol", `contract B {}`)
+	writeSource(t, dir, "C.sol", `contract C {}`)
+
+	compiler := &fakeCompiler{}
+
+	p, err := NewProject(WithContractsDir(dir), WithCompiler(compiler.factory))
+	require.NoError(t, err)
+
+	res, err := p.Compile()
+	require.NoError(t, err)
+	require.Len(t, res.Runs, 2)
+	require.ElementsMatch(t, []string{"A.sol:A", "B.sol:B", "C.sol:C"}, res.Contracts)
+	require.ElementsMatch(t, [][]string{{"A.sol", "B.sol"}, {"C.sol"}}, compiler.compiled())
+	require.Equal(t, []string{"0.8.4", "0.8.4"}, compiler.versions)
+
+	// the sources are sent with their content
+	for _, input := range compiler.inputs {
+		for _, src := range input.Sources {
+			require.NotEmpty(t, src.Content)
+		}
+	}
+
+	// the artifacts are written
+	_, err = os.Stat(filepath.Join(dir, "out", "A.sol", "A.json"))
+	require.NoError(t, err)
+
+	// only the component of the modified file is compiled again
+	writeSource(t, dir, "C.sol", `contract C { uint256 a; }`)
+
+	res, err = p.Compile()
+	require.NoError(t, err)
+	require.Equal(t, []string{"C.sol:C"}, res.Contracts)
+	require.Equal(t, []string{"C.sol"}, compiler.compiled()[2])
+}
+
+func TestSolcCompiler_Cancel(t *testing.T) {
+	// the child process keeps the output open if only solc is killed
+	path := filepath.Join(t.TempDir(), "solc")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nsleep 30 &\nsleep 30\n"), 0755))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	now := time.Now()
+	_, err := CompileContext(ctx, path, "", NewStandardInputFromSources(map[string]string{}, DefaultConfig()))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(now), 10*time.Second)
+}
+
+func TestProject_CompileContext(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSource(t, dir, "A.sol", `contract A {}`)
+	writeSource(t, dir, "Slow.sol", `contract Slow {}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	compiler := &fakeCompiler{
+		hook: func(ctx context.Context, input *StandardInput) error {
+			if _, ok := input.Sources["Slow.sol"]; ok {
+				cancel()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+
+	p, err := NewProject(WithContractsDir(dir), WithCompiler(compiler.factory))
+	require.NoError(t, err)
+
+	// the components that finished are returned
+	res, err := p.CompileContext(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, res)
+	require.NotContains(t, res.Contracts, "Slow.sol:Slow")
+
+	for _, name := range res.Contracts {
+		_, err = os.Stat(filepath.Join(dir, "out", strings.Replace(name, ":", "/", -1)+".json"))
+		require.NoError(t, err)
+	}
+	compiled := res.Contracts
+
+	// the components that did not finish are compiled the next time
+	compiler.hook = nil
+
+	res, err = p.CompileContext(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, res.Contracts, "Slow.sol:Slow")
+	require.ElementsMatch(t, []string{"A.sol:A", "Slow.sol:Slow"}, append(compiled, res.Contracts...))
+}
+
+func TestProject_Parallelism(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"A", "B", "C"} {
+		content := "pragma solidity ^0.8.0;\ncontract " + name + " {}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".sol"), []byte(content), 0644))
+	}
+
+	// each compilation waits until all the components are being compiled
+	var started sync.WaitGroup
+	started.Add(3)
+
+	compiler := &fakeCompiler{
+		hook: func(ctx context.Context, input *StandardInput) error {
+			started.Done()
+
+			done := make(chan struct{})
+			go func() {
+				started.Wait()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("components not compiled in parallel")
+			}
+		},
+	}
+
+	p, err := NewProject(WithContractsDir(dir), WithCompiler(compiler.factory), WithParallelism(3))
+	require.NoError(t, err)
+
+	res, err := p.Compile()
+	require.NoError(t, err)
+	require.Len(t, res.Runs, 3)
+	require.ElementsMatch(t, []string{"A.sol:A", "B.sol:B", "C.sol:C"}, res.Contracts)
+
+	for _, run := range res.Runs {
+		require.False(t, run.StartTime.IsZero())
+	}
+
+	contracts, err := p.ListContracts()
+	require.NoError(t, err)
+	require.Len(t, contracts, 3)
+}
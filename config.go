@@ -1,5 +1,7 @@
 package gosolc
 
+import "github.com/umbracle/gosolc/svm"
+
 const (
 	defaultSolidityVersion = "0.8.4"
 )
@@ -10,6 +12,73 @@ type Config struct {
 	ArtifactsDir    string
 	SolidityVersion string
 	Runs            uint64
+
+	// Optimizer enables the optimizer. NewProject enables it if Runs is
+	// not zero and WithOptimizer is not used.
+	Optimizer bool
+
+	// optimizerSet is true if the optimizer was set with WithOptimizer
+	optimizerSet bool
+
+	// OptimizerDetails configures each of the optimizer components
+	OptimizerDetails *OptimizerDetails
+
+	// EVMVersion is the EVM version to compile for (i.e. london, paris)
+	EVMVersion string
+
+	// ViaIR compiles the contracts through the Yul IR pipeline
+	ViaIR bool
+
+	// RevertStrings sets how the revert strings are generated
+	// (default, strip, debug or verboseDebug)
+	RevertStrings string
+
+	// BytecodeHash is the hash of the metadata appended to the
+	// bytecode (ipfs, bzzr1 or none)
+	BytecodeHash string
+
+	// AppendCBOR appends the CBOR metadata to the bytecode if set
+	AppendCBOR *bool
+
+	// OutputSelection are the outputs requested for each contract in
+	// addition to the default ones (i.e. OutputStorageLayout)
+	OutputSelection []string
+
+	// AutoVersion compiles each component with the newest compiler that
+	// satisfies its pragmas if SolidityVersion does not
+	AutoVersion bool
+
+	// WarningsAsErrors fails the compilation if there are warnings
+	WarningsAsErrors bool
+
+	// IgnoredErrorCodes are the codes of the warnings and infos
+	// that are not reported
+	IgnoredErrorCodes []string
+
+	// Remappings are the import remappings with the format [context:]prefix=target.
+	// The remappings in the remappings.txt file of the contracts directory
+	// are loaded too.
+	Remappings []string
+
+	// IncludePaths are the directories, besides the contracts directory, where
+	// the imported sources are looked up (i.e. node_modules or lib)
+	IncludePaths []string
+
+	// Libraries are the addresses of the deployed libraries indexed by their
+	// fully qualified name (<path>:<library>). The compiler links them directly.
+	Libraries map[string]string
+
+	// Compiler returns the compiler used for each solc version. By
+	// default, the solc binaries of the version manager are run.
+	Compiler CompilerFactory
+
+	// Parallelism is the number of components compiled at the same
+	// time. It defaults to the number of CPUs.
+	Parallelism int
+
+	// SVMOptions configure the version manager that installs the solc
+	// binaries (i.e. svm.WithDir or svm.WithOffline)
+	SVMOptions []svm.Option
 }
 
 func DefaultConfig() *Config {
@@ -39,8 +108,118 @@ func WithContractsDir(contractsDir string) Option {
 	}
 }
 
+// WithRuns sets the optimizer runs. The optimizer is enabled if runs is not
+// zero, unless WithOptimizer is used.
 func WithRuns(runs uint64) Option {
 	return func(c *Config) {
 		c.Runs = runs
 	}
 }
+
+func WithOptimizer(enabled bool) Option {
+	return func(c *Config) {
+		c.Optimizer = enabled
+		c.optimizerSet = true
+	}
+}
+
+func WithOptimizerDetails(details *OptimizerDetails) Option {
+	return func(c *Config) {
+		c.OptimizerDetails = details
+	}
+}
+
+func WithEVMVersion(evmVersion string) Option {
+	return func(c *Config) {
+		c.EVMVersion = evmVersion
+	}
+}
+
+func WithViaIR(viaIR bool) Option {
+	return func(c *Config) {
+		c.ViaIR = viaIR
+	}
+}
+
+func WithRevertStrings(revertStrings string) Option {
+	return func(c *Config) {
+		c.RevertStrings = revertStrings
+	}
+}
+
+func WithBytecodeHash(bytecodeHash string) Option {
+	return func(c *Config) {
+		c.BytecodeHash = bytecodeHash
+	}
+}
+
+func WithAppendCBOR(appendCBOR bool) Option {
+	return func(c *Config) {
+		c.AppendCBOR = &appendCBOR
+	}
+}
+
+func WithAutoVersion(autoVersion bool) Option {
+	return func(c *Config) {
+		c.AutoVersion = autoVersion
+	}
+}
+
+func WithWarningsAsErrors(warningsAsErrors bool) Option {
+	return func(c *Config) {
+		c.WarningsAsErrors = warningsAsErrors
+	}
+}
+
+func WithIgnoredErrorCodes(codes ...string) Option {
+	return func(c *Config) {
+		c.IgnoredErrorCodes = append(c.IgnoredErrorCodes, codes...)
+	}
+}
+
+func WithOutputSelection(outputs ...string) Option {
+	return func(c *Config) {
+		c.OutputSelection = append(c.OutputSelection, outputs...)
+	}
+}
+
+func WithRemappings(remappings ...string) Option {
+	return func(c *Config) {
+		c.Remappings = append(c.Remappings, remappings...)
+	}
+}
+
+func WithIncludePaths(paths ...string) Option {
+	return func(c *Config) {
+		c.IncludePaths = append(c.IncludePaths, paths...)
+	}
+}
+
+func WithLibraries(libraries map[string]string) Option {
+	return func(c *Config) {
+		if c.Libraries == nil {
+			c.Libraries = map[string]string{}
+		}
+		for name, addr := range libraries {
+			c.Libraries[name] = addr
+		}
+	}
+}
+
+func WithCompiler(compiler CompilerFactory) Option {
+	return func(c *Config) {
+		c.Compiler = compiler
+	}
+}
+
+func WithSVMOptions(opts ...svm.Option) Option {
+	return func(c *Config) {
+		c.SVMOptions = append(c.SVMOptions, opts...)
+	}
+}
+
+func WithParallelism(parallelism int) Option {
+	return func(c *Config) {
+		c.Parallelism = parallelism
+	}
+}
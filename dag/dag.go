@@ -87,6 +87,9 @@ func (d *Dag) AddEdge(e Edge) {
 	s.(set).add(e.Dst)
 }
 
+// FindComponents returns a component for each root vertex (a vertex without
+// inbound edges) with the root and all the vertices reachable from it. The
+// root is the first vertex of each component.
 func (d *Dag) FindComponents() [][]Vertex {
 
 	// find components without any inbound
@@ -101,23 +104,47 @@ func (d *Dag) FindComponents() [][]Vertex {
 
 	// follow each leaf vertex upwards to find the component
 	for _, leaf := range leafVertex {
-		component := []Vertex{}
-
-		queue := []Vertex{leaf}
-		for len(queue) != 0 {
-			var item Vertex
-			item, queue = queue[0], queue[1:]
-
-			component = append(component, item)
-			if outbound, ok := d.outbound[item]; ok {
-				for v := range outbound.(set) {
-					queue = append(queue, v)
-				}
+		result = append(result, d.walk(d.outbound, leaf))
+	}
+	return result
+}
+
+// FindAncestors returns the vertices with a path to any of the given
+// vertices (i.e. the files that import them transitively), including them
+func (d *Dag) FindAncestors(vs ...Vertex) []Vertex {
+	return d.walk(d.inbound, vs...)
+}
+
+// FindDescendants returns the vertices reachable from any of the given
+// vertices (i.e. the files they import transitively), including them
+func (d *Dag) FindDescendants(vs ...Vertex) []Vertex {
+	return d.walk(d.outbound, vs...)
+}
+
+// walk returns the vertices reachable from the given ones following the
+// edges. Each vertex is returned once even if there are cycles.
+func (d *Dag) walk(edges set, vs ...Vertex) []Vertex {
+	res := []Vertex{}
+	visited := map[Vertex]struct{}{}
+
+	queue := append([]Vertex{}, vs...)
+	for len(queue) != 0 {
+		var item Vertex
+		item, queue = queue[0], queue[1:]
+
+		if _, ok := visited[item]; ok {
+			continue
+		}
+		visited[item] = struct{}{}
+
+		res = append(res, item)
+		if next, ok := edges[item]; ok {
+			for v := range next.(set) {
+				queue = append(queue, v)
 			}
 		}
-		result = append(result, component)
 	}
-	return result
+	return res
 }
 
 type set map[interface{}]interface{}
@@ -37,3 +37,54 @@ func TestDag_FindComponents(t *testing.T) {
 
 	d.FindComponents()
 }
+
+func TestDag_Diamond(t *testing.T) {
+	// 1 imports 2 and 3 that both import 4
+	d := &Dag{}
+	for i := 1; i <= 5; i++ {
+		d.AddVertex(i)
+	}
+	d.AddEdge(Edge{Src: 1, Dst: 2})
+	d.AddEdge(Edge{Src: 1, Dst: 3})
+	d.AddEdge(Edge{Src: 2, Dst: 4})
+	d.AddEdge(Edge{Src: 3, Dst: 4})
+	d.AddEdge(Edge{Src: 5, Dst: 3})
+
+	components := d.FindComponents()
+	assert.Len(t, components, 2)
+
+	for _, comp := range components {
+		switch comp[0] {
+		case 1:
+			// the shared vertex is included once
+			assert.ElementsMatch(t, []Vertex{1, 2, 3, 4}, comp)
+		case 5:
+			assert.ElementsMatch(t, []Vertex{5, 3, 4}, comp)
+		default:
+			t.Fatalf("unexpected root %v", comp[0])
+		}
+	}
+
+	assert.ElementsMatch(t, []Vertex{4, 2, 3, 1, 5}, d.FindAncestors(4))
+	assert.ElementsMatch(t, []Vertex{2, 1}, d.FindAncestors(2))
+	assert.ElementsMatch(t, []Vertex{2, 3, 1, 5}, d.FindAncestors(2, 3))
+	assert.ElementsMatch(t, []Vertex{1}, d.FindAncestors(1))
+}
+
+func TestDag_Cycle(t *testing.T) {
+	d := &Dag{}
+	for i := 1; i <= 3; i++ {
+		d.AddVertex(i)
+	}
+	d.AddEdge(Edge{Src: 1, Dst: 2})
+	d.AddEdge(Edge{Src: 2, Dst: 3})
+	d.AddEdge(Edge{Src: 3, Dst: 2})
+
+	components := d.FindComponents()
+	assert.Len(t, components, 1)
+	assert.ElementsMatch(t, []Vertex{1, 2, 3}, components[0])
+
+	assert.ElementsMatch(t, []Vertex{3, 2, 1}, d.FindAncestors(3))
+	assert.ElementsMatch(t, []Vertex{3, 2}, d.FindDescendants(3))
+	assert.ElementsMatch(t, []Vertex{1, 2, 3}, d.FindDescendants(1))
+}
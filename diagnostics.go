@@ -0,0 +1,95 @@
+package gosolc
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Severity is the severity of a compiler diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Diagnostic is an error, warning or info message reported by the compiler
+type Diagnostic struct {
+	// Severity is the severity of the diagnostic (error, warning or info)
+	Severity Severity `json:"severity"`
+
+	// Type is the type of the diagnostic (i.e. TypeError, Warning)
+	Type string `json:"type"`
+
+	// ErrorCode is the unique code of the diagnostic
+	ErrorCode string `json:"errorCode"`
+
+	// Component is the compiler component that reported the diagnostic
+	Component string `json:"component"`
+
+	// Message is the message of the diagnostic
+	Message string `json:"message"`
+
+	// FormattedMessage is the message formatted with the source location
+	FormattedMessage string `json:"formattedMessage"`
+
+	// SourceLocation is the location in the source of the diagnostic
+	SourceLocation *SourceLocation `json:"sourceLocation,omitempty"`
+
+	// SecondarySourceLocations are other locations related to the diagnostic
+	SecondarySourceLocations []*SourceLocation `json:"secondarySourceLocations,omitempty"`
+}
+
+func (d *Diagnostic) String() string {
+	if d.FormattedMessage != "" {
+		return d.FormattedMessage
+	}
+	if d.SourceLocation != nil {
+		return fmt.Sprintf("%s:%d:%d: %s: %s", d.SourceLocation.File, d.SourceLocation.Start, d.SourceLocation.End, d.Type, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.Type, d.Message)
+}
+
+// SourceLocation is a range of bytes in a source file
+type SourceLocation struct {
+	File    string `json:"file"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Message string `json:"message,omitempty"`
+}
+
+// DiagnosticsError is returned when the compilation fails because
+// of the reported diagnostics
+type DiagnosticsError struct {
+	Diagnostics []*Diagnostic
+}
+
+func (e *DiagnosticsError) Error() string {
+	var err error
+	for _, d := range e.Diagnostics {
+		err = multierror.Append(err, fmt.Errorf("%s", d.String()))
+	}
+	if err == nil {
+		return "compilation failed"
+	}
+	return err.Error()
+}
+
+// filterDiagnostics returns the diagnostics whose error code is not ignored
+func filterDiagnostics(diagnostics []*Diagnostic, ignoredCodes []string) []*Diagnostic {
+	res := []*Diagnostic{}
+	for _, d := range diagnostics {
+		ignored := false
+		for _, code := range ignoredCodes {
+			if d.ErrorCode == code {
+				ignored = true
+			}
+		}
+		if !ignored {
+			res = append(res, d)
+		}
+	}
+	return res
+}
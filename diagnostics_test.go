@@ -0,0 +1,107 @@
+package gosolc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const diagnosticsOutput = `{
+	"errors": [
+		{
+			"component": "general",
+			"errorCode": "1878",
+			"formattedMessage": "Warning: SPDX license identifier not provided in source file.",
+			"message": "SPDX license identifier not provided in source file.",
+			"severity": "warning",
+			"sourceLocation": {
+				"end": -1,
+				"file": "A.sol",
+				"start": -1
+			},
+			"type": "Warning"
+		},
+		{
+			"component": "general",
+			"errorCode": "2072",
+			"formattedMessage": "Warning: Unused local variable.",
+			"message": "Unused local variable.",
+			"severity": "warning",
+			"sourceLocation": {
+				"end": 120,
+				"file": "A.sol",
+				"start": 110
+			},
+			"type": "Warning"
+		}
+	],
+	"contracts": {},
+	"sources": {}
+}`
+
+const errorOutput = `{
+	"errors": [
+		{
+			"component": "general",
+			"errorCode": "7576",
+			"formattedMessage": "DeclarationError: Undeclared identifier.",
+			"message": "Undeclared identifier.",
+			"severity": "error",
+			"sourceLocation": {
+				"end": 20,
+				"file": "A.sol",
+				"start": 10
+			},
+			"secondarySourceLocations": [
+				{
+					"end": 40,
+					"file": "B.sol",
+					"start": 30,
+					"message": "Did you mean this?"
+				}
+			],
+			"type": "DeclarationError"
+		}
+	]
+}`
+
+func TestCompile_Diagnostics(t *testing.T) {
+	input := NewStandardInput([]string{"A.sol"}, DefaultConfig())
+
+	// warnings do not fail the compilation
+	output, err := Compile(fakeSolc(t, diagnosticsOutput), ".", input)
+	require.NoError(t, err)
+	require.Len(t, output.Errors, 2)
+
+	d := output.Errors[1]
+	require.Equal(t, SeverityWarning, d.Severity)
+	require.Equal(t, "2072", d.ErrorCode)
+	require.Equal(t, &SourceLocation{File: "A.sol", Start: 110, End: 120}, d.SourceLocation)
+
+	// errors fail the compilation
+	_, err = Compile(fakeSolc(t, errorOutput), ".", input)
+	require.Error(t, err)
+
+	var diagErr *DiagnosticsError
+	require.ErrorAs(t, err, &diagErr)
+	require.Len(t, diagErr.Diagnostics, 1)
+
+	d = diagErr.Diagnostics[0]
+	require.Equal(t, SeverityError, d.Severity)
+	require.Equal(t, "DeclarationError", d.Type)
+	require.Len(t, d.SecondarySourceLocations, 1)
+	require.Equal(t, "Did you mean this?", d.SecondarySourceLocations[0].Message)
+	require.Contains(t, err.Error(), "DeclarationError: Undeclared identifier.")
+}
+
+func TestFilterDiagnostics(t *testing.T) {
+	diagnostics := []*Diagnostic{
+		{ErrorCode: "1878"},
+		{ErrorCode: "2072"},
+		{ErrorCode: "5667"},
+	}
+
+	res := filterDiagnostics(diagnostics, []string{"1878", "5667"})
+	require.Len(t, res, 1)
+	require.Equal(t, "2072", res[0].ErrorCode)
+}
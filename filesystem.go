@@ -8,11 +8,16 @@ import (
 )
 
 type fileRef struct {
+	// root is the directory the path is relative to
+	root    string
 	path    string
 	modTime time.Time
+	size    int64
 }
 
-func readDir(dirPath string) ([]*fileRef, error) {
+// readDir returns the Solidity files in dirPath. The directories in skip
+// (i.e. include paths inside the contracts directory) are not walked.
+func readDir(dirPath string, skip ...string) ([]*fileRef, error) {
 	files := []*fileRef{}
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
@@ -20,6 +25,11 @@ func readDir(dirPath string) ([]*fileRef, error) {
 			return err
 		}
 		if info.IsDir() {
+			for _, dir := range skip {
+				if filepath.Clean(path) == filepath.Clean(dir) {
+					return filepath.SkipDir
+				}
+			}
 			return nil
 		}
 
@@ -32,8 +42,10 @@ func readDir(dirPath string) ([]*fileRef, error) {
 		path = strings.TrimPrefix(path, dirPath+"/")
 
 		files = append(files, &fileRef{
+			root:    dirPath,
 			path:    path,
 			modTime: info.ModTime(),
+			size:    info.Size(),
 		})
 		return nil
 	})
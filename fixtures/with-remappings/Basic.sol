@@ -0,0 +1,10 @@
+// SPDX-License-Identifier: UNLICENSED
+pragma solidity >=0.8.0;
+
+import "@deps/Dependency.sol";
+
+contract Simple is Dependency {
+    function simple() public pure returns (uint256) {
+        return 0;
+    }
+}
@@ -0,0 +1,160 @@
+package gosolc
+
+import (
+	"encoding/hex"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// StandardInput is the standard JSON input of the compiler
+type StandardInput struct {
+	Language string                  `json:"language"`
+	Sources  map[string]*InputSource `json:"sources"`
+	Settings *Settings               `json:"settings"`
+}
+
+// InputSource is a source unit of the standard JSON input. The source
+// is either loaded from the urls or given inline with the content.
+type InputSource struct {
+	Keccak256 string   `json:"keccak256,omitempty"`
+	URLs      []string `json:"urls,omitempty"`
+	Content   string   `json:"content,omitempty"`
+}
+
+// Settings are the compiler settings of the standard JSON input
+type Settings struct {
+	Remappings []string          `json:"remappings,omitempty"`
+	Optimizer  *Optimizer        `json:"optimizer,omitempty"`
+	EVMVersion string            `json:"evmVersion,omitempty"`
+	ViaIR      bool              `json:"viaIR,omitempty"`
+	Debug      *DebugSettings    `json:"debug,omitempty"`
+	Metadata   *MetadataSettings `json:"metadata,omitempty"`
+
+	// Libraries are the addresses of the libraries indexed
+	// by source file and library name
+	Libraries map[string]map[string]string `json:"libraries,omitempty"`
+
+	OutputSelection OutputSelection `json:"outputSelection"`
+}
+
+type Optimizer struct {
+	Enabled bool              `json:"enabled"`
+	Runs    uint64            `json:"runs"`
+	Details *OptimizerDetails `json:"details,omitempty"`
+}
+
+// OptimizerDetails switches on or off each of the optimizer components.
+// The components that are not set use the default of the compiler.
+type OptimizerDetails struct {
+	Peephole          *bool       `json:"peephole,omitempty"`
+	Inliner           *bool       `json:"inliner,omitempty"`
+	JumpdestRemover   *bool       `json:"jumpdestRemover,omitempty"`
+	OrderLiterals     *bool       `json:"orderLiterals,omitempty"`
+	Deduplicate       *bool       `json:"deduplicate,omitempty"`
+	Cse               *bool       `json:"cse,omitempty"`
+	ConstantOptimizer *bool       `json:"constantOptimizer,omitempty"`
+	Yul               *bool       `json:"yul,omitempty"`
+	YulDetails        *YulDetails `json:"yulDetails,omitempty"`
+}
+
+type YulDetails struct {
+	StackAllocation *bool  `json:"stackAllocation,omitempty"`
+	OptimizerSteps  string `json:"optimizerSteps,omitempty"`
+}
+
+type DebugSettings struct {
+	RevertStrings string `json:"revertStrings,omitempty"`
+}
+
+type MetadataSettings struct {
+	BytecodeHash string `json:"bytecodeHash,omitempty"`
+	AppendCBOR   *bool  `json:"appendCBOR,omitempty"`
+}
+
+// OutputSelection are the outputs requested for each source file and contract.
+// The empty contract name selects the outputs of the source file (i.e. ast).
+type OutputSelection map[string]map[string][]string
+
+// NewStandardInput returns the standard JSON input used to compile
+// the files of the project with the given configuration
+func NewStandardInput(files []string, config *Config) *StandardInput {
+	sources := map[string]*InputSource{}
+	for _, file := range files {
+		sources[file] = &InputSource{
+			URLs: []string{file},
+		}
+	}
+	return newStandardInput(sources, config)
+}
+
+// NewStandardInputFromSources returns the standard JSON input used to compile the
+// in-memory sources, indexed by source unit name, with the given configuration
+func NewStandardInputFromSources(contents map[string]string, config *Config) *StandardInput {
+	sources := map[string]*InputSource{}
+	for file, content := range contents {
+		sources[file] = &InputSource{
+			Keccak256: keccak256Hex([]byte(content)),
+			Content:   content,
+		}
+	}
+	return newStandardInput(sources, config)
+}
+
+func newStandardInput(sources map[string]*InputSource, config *Config) *StandardInput {
+	input := &StandardInput{
+		Language: "Solidity",
+		Sources:  sources,
+		Settings: &Settings{
+			Remappings: config.Remappings,
+			Optimizer: &Optimizer{
+				Enabled: config.Optimizer,
+				Runs:    config.Runs,
+				Details: config.OptimizerDetails,
+			},
+			EVMVersion: config.EVMVersion,
+			ViaIR:      config.ViaIR,
+			OutputSelection: OutputSelection{
+				"*": {
+					"": {
+						"ast",
+					},
+					"*": unique(append(append([]string{}, defaultOutputSelection...), config.OutputSelection...)),
+				},
+			},
+		},
+	}
+	if len(config.Libraries) != 0 {
+		libraries := map[string]map[string]string{}
+		for name, addr := range config.Libraries {
+			file, library, err := splitLibraryName(name)
+			if err != nil {
+				// the libraries are validated when the project is created
+				continue
+			}
+			if libraries[file] == nil {
+				libraries[file] = map[string]string{}
+			}
+			libraries[file][library] = addr
+		}
+		input.Settings.Libraries = libraries
+	}
+	if config.RevertStrings != "" {
+		input.Settings.Debug = &DebugSettings{
+			RevertStrings: config.RevertStrings,
+		}
+	}
+	if config.BytecodeHash != "" || config.AppendCBOR != nil {
+		input.Settings.Metadata = &MetadataSettings{
+			BytecodeHash: config.BytecodeHash,
+			AppendCBOR:   config.AppendCBOR,
+		}
+	}
+	return input
+}
+
+// keccak256Hex returns the 0x prefixed keccak256 hash of data
+func keccak256Hex(data []byte) string {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return "0x" + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,108 @@
+package gosolc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandardInput_Marshal(t *testing.T) {
+	input := NewStandardInput([]string{"a&b/<c>.sol"}, &Config{Runs: 200, Optimizer: true})
+
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+
+	require.Equal(t, "Solidity", raw["language"])
+
+	sources := raw["sources"].(map[string]interface{})
+	require.Contains(t, sources, "a&b/<c>.sol")
+	require.Equal(t, map[string]interface{}{
+		"urls": []interface{}{"a&b/<c>.sol"},
+	}, sources["a&b/<c>.sol"])
+
+	settings := raw["settings"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"enabled": true,
+		"runs":    float64(200),
+	}, settings["optimizer"])
+	require.NotContains(t, settings, "libraries")
+	require.NotContains(t, settings, "remappings")
+}
+
+func TestStandardInput_Settings(t *testing.T) {
+	cfg := DefaultConfig()
+
+	opts := []Option{
+		WithOptimizer(true),
+		WithRuns(0),
+		WithOptimizerDetails(&OptimizerDetails{
+			Yul: boolPtr(false),
+			YulDetails: &YulDetails{
+				OptimizerSteps: "dhfoDgvulfnTUtnIf",
+			},
+		}),
+		WithEVMVersion("paris"),
+		WithViaIR(true),
+		WithRevertStrings("strip"),
+		WithBytecodeHash("none"),
+		WithAppendCBOR(false),
+		WithLibraries(map[string]string{
+			"lib/Math.sol:Math": "0x1111111111111111111111111111111111111111",
+		}),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	data, err := json.Marshal(NewStandardInput([]string{"A.sol"}, cfg).Settings)
+	require.NoError(t, err)
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+
+	expected := map[string]interface{}{
+		"optimizer": map[string]interface{}{
+			"enabled": true,
+			"runs":    float64(0),
+			"details": map[string]interface{}{
+				"yul": false,
+				"yulDetails": map[string]interface{}{
+					"optimizerSteps": "dhfoDgvulfnTUtnIf",
+				},
+			},
+		},
+		"evmVersion": "paris",
+		"viaIR":      true,
+		"debug": map[string]interface{}{
+			"revertStrings": "strip",
+		},
+		"metadata": map[string]interface{}{
+			"bytecodeHash": "none",
+			"appendCBOR":   false,
+		},
+		"libraries": map[string]interface{}{
+			"lib/Math.sol": map[string]interface{}{
+				"Math": "0x1111111111111111111111111111111111111111",
+			},
+		},
+	}
+	for k, v := range expected {
+		require.Equal(t, v, raw[k], k)
+	}
+}
+
+func TestStandardInput_Sources(t *testing.T) {
+	input := NewStandardInputFromSources(map[string]string{"A.sol": ""}, DefaultConfig())
+
+	require.Equal(t, &InputSource{
+		Keccak256: "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+	}, input.Sources["A.sol"])
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
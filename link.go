@@ -0,0 +1,113 @@
+package gosolc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LinkReferences are the positions in the bytecode of the addresses of the
+// libraries that are not linked yet, indexed by source file and library name
+type LinkReferences map[string]map[string][]*CodeRange
+
+// Libraries returns the fully qualified names (<path>:<library>) of the libraries
+func (l LinkReferences) Libraries() []string {
+	res := []string{}
+	for file, libraries := range l {
+		for name := range libraries {
+			res = append(res, file+":"+name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// UnlinkedLibraries returns the fully qualified names of the libraries
+// that have to be linked before the bytecode is deployed
+func (b *Bytecode) UnlinkedLibraries() []string {
+	return b.LinkReferences.Libraries()
+}
+
+// UnlinkedLibraries returns the fully qualified names of the libraries that have to
+// be linked in either the creation or the deployed bytecode of the contract
+func (c *Contract) UnlinkedLibraries() []string {
+	res := []string{}
+	if c.Bytecode != nil {
+		res = append(res, c.Bytecode.UnlinkedLibraries()...)
+	}
+	if c.DeployedBytecode != nil {
+		res = append(res, c.DeployedBytecode.UnlinkedLibraries()...)
+	}
+	res = unique(res)
+	sort.Strings(res)
+	return res
+}
+
+// Link returns a copy of the bytecode with the placeholders of the libraries
+// replaced by their addresses. The libraries are indexed by their fully qualified
+// name (<path>:<library>). The libraries not in the map remain unlinked.
+func Link(bytecode *Bytecode, libraries map[string]string) (*Bytecode, error) {
+	res := *bytecode
+	res.LinkReferences = LinkReferences{}
+
+	hasPrefix := strings.HasPrefix(bytecode.Object, "0x")
+	object := []byte(strings.TrimPrefix(bytecode.Object, "0x"))
+
+	for file, refs := range bytecode.LinkReferences {
+		for name, ranges := range refs {
+			addr, ok := libraries[file+":"+name]
+			if !ok {
+				if res.LinkReferences[file] == nil {
+					res.LinkReferences[file] = map[string][]*CodeRange{}
+				}
+				res.LinkReferences[file][name] = ranges
+				continue
+			}
+
+			addrHex, err := parseAddress(addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address for library '%s:%s': %v", file, name, err)
+			}
+			for _, r := range ranges {
+				start, end := 2*r.Start, 2*(r.Start+r.Length)
+				if r.Length != 20 || start < 0 || end > len(object) {
+					return nil, fmt.Errorf("invalid link reference %d:%d for library '%s:%s'", r.Start, r.Length, file, name)
+				}
+				copy(object[start:end], addrHex)
+			}
+		}
+	}
+
+	res.Object = string(object)
+	if hasPrefix {
+		res.Object = "0x" + res.Object
+	}
+	if bytecode.LinkReferences == nil {
+		res.LinkReferences = nil
+	}
+	return &res, nil
+}
+
+// parseAddress validates a hex encoded address and
+// returns it without the 0x prefix in lower case
+func parseAddress(addr string) (string, error) {
+	addr = strings.TrimPrefix(strings.ToLower(addr), "0x")
+	buf, err := hex.DecodeString(addr)
+	if err != nil {
+		return "", err
+	}
+	if len(buf) != 20 {
+		return "", fmt.Errorf("address must be 20 bytes but it is %d", len(buf))
+	}
+	return addr, nil
+}
+
+// splitLibraryName splits a fully qualified library name into its source file and name
+func splitLibraryName(fqn string) (string, string, error) {
+	indx := strings.LastIndex(fqn, ":")
+	if indx <= 0 || indx == len(fqn)-1 {
+		return "", "", fmt.Errorf("library '%s' is not in the format <path>:<library>", fqn)
+	}
+	return fqn[:indx], fqn[indx+1:], nil
+}
@@ -0,0 +1,84 @@
+package gosolc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLink(t *testing.T) {
+	placeholder := "__$" + strings.Repeat("a", 34) + "$__"
+	object := "6080" + placeholder + "6000" + placeholder + "00" + strings.Replace(placeholder, "a", "b", -1)
+
+	var bytecode *Bytecode
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"object": "`+object+`",
+		"linkReferences": {
+			"lib/Math.sol": {
+				"Math": [{"start": 2, "length": 20}, {"start": 24, "length": 20}]
+			},
+			"lib/Strings.sol": {
+				"Strings": [{"start": 45, "length": 20}]
+			}
+		}
+	}`), &bytecode))
+
+	require.Equal(t, []string{"lib/Math.sol:Math", "lib/Strings.sol:Strings"}, bytecode.UnlinkedLibraries())
+
+	addr := strings.Repeat("12", 20)
+
+	// link only one of the libraries
+	linked, err := Link(bytecode, map[string]string{
+		"lib/Math.sol:Math": "0x" + strings.ToUpper(addr),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "6080"+addr+"6000"+addr+"00"+strings.Replace(placeholder, "a", "b", -1), linked.Object)
+	require.Equal(t, []string{"lib/Strings.sol:Strings"}, linked.UnlinkedLibraries())
+
+	// the original bytecode is not modified
+	require.Equal(t, object, bytecode.Object)
+	require.Len(t, bytecode.UnlinkedLibraries(), 2)
+
+	linked, err = Link(linked, map[string]string{
+		"lib/Strings.sol:Strings": addr,
+	})
+	require.NoError(t, err)
+	require.NotContains(t, linked.Object, "__")
+	require.Empty(t, linked.UnlinkedLibraries())
+
+	// invalid address
+	_, err = Link(bytecode, map[string]string{
+		"lib/Math.sol:Math": "0x1234",
+	})
+	require.Error(t, err)
+}
+
+func TestContract_UnlinkedLibraries(t *testing.T) {
+	c := &Contract{
+		Bytecode: &Bytecode{
+			LinkReferences: LinkReferences{
+				"A.sol": {"A": {{Start: 0, Length: 20}}},
+				"B.sol": {"B": {{Start: 20, Length: 20}}},
+			},
+		},
+		DeployedBytecode: &Bytecode{
+			LinkReferences: LinkReferences{
+				"A.sol": {"A": {{Start: 0, Length: 20}}},
+			},
+		},
+	}
+	require.Equal(t, []string{"A.sol:A", "B.sol:B"}, c.UnlinkedLibraries())
+}
+
+func TestProject_Libraries(t *testing.T) {
+	_, err := NewProject(WithLibraries(map[string]string{"Math": "0x1111111111111111111111111111111111111111"}))
+	require.Error(t, err)
+
+	_, err = NewProject(WithLibraries(map[string]string{"A.sol:Math": "0x11"}))
+	require.Error(t, err)
+
+	_, err = NewProject(WithLibraries(map[string]string{"A.sol:Math": "0x1111111111111111111111111111111111111111"}))
+	require.NoError(t, err)
+}
@@ -0,0 +1,108 @@
+package gosolc
+
+// Outputs of the compiler that can be requested in addition to the default
+// ones (abi, bytecode, deployed bytecode, method identifiers and metadata)
+const (
+	OutputStorageLayout       = "storageLayout"
+	OutputGasEstimates        = "evm.gasEstimates"
+	OutputIR                  = "ir"
+	OutputIROptimized         = "irOptimized"
+	OutputAssembly            = "evm.assembly"
+	OutputLegacyAssembly      = "evm.legacyAssembly"
+	OutputDevDoc              = "devdoc"
+	OutputUserDoc             = "userdoc"
+	OutputImmutableReferences = "evm.deployedBytecode.immutableReferences"
+	OutputFunctionDebugData   = "evm.bytecode.functionDebugData"
+)
+
+var defaultOutputSelection = []string{
+	"abi",
+	"evm.bytecode",
+	"evm.deployedBytecode",
+	"evm.methodIdentifiers",
+	"metadata",
+}
+
+// StorageLayout is the layout of the state variables of a contract in storage
+type StorageLayout struct {
+	Storage []*StorageVariable      `json:"storage"`
+	Types   map[string]*StorageType `json:"types"`
+}
+
+// StorageVariable is a state variable (or struct member) in storage
+type StorageVariable struct {
+	AstID    int    `json:"astId"`
+	Contract string `json:"contract"`
+	Label    string `json:"label"`
+	Offset   int    `json:"offset"`
+	Slot     string `json:"slot"`
+	Type     string `json:"type"`
+}
+
+// StorageType is a type referenced in the storage layout
+type StorageType struct {
+	Encoding      string             `json:"encoding"`
+	Label         string             `json:"label"`
+	NumberOfBytes string             `json:"numberOfBytes"`
+	Base          string             `json:"base,omitempty"`
+	Key           string             `json:"key,omitempty"`
+	Value         string             `json:"value,omitempty"`
+	Members       []*StorageVariable `json:"members,omitempty"`
+}
+
+// GasEstimates are the gas costs estimated by the compiler. The costs
+// are decimal numbers or "infinite" if they cannot be bounded.
+type GasEstimates struct {
+	Creation map[string]string `json:"creation"`
+	External map[string]string `json:"external"`
+	Internal map[string]string `json:"internal"`
+}
+
+// DevDoc is the developer documentation of a contract
+type DevDoc struct {
+	Kind           string                    `json:"kind"`
+	Version        int                       `json:"version"`
+	Title          string                    `json:"title,omitempty"`
+	Author         string                    `json:"author,omitempty"`
+	Details        string                    `json:"details,omitempty"`
+	Methods        map[string]*DevDocEntry   `json:"methods,omitempty"`
+	Events         map[string]*DevDocEntry   `json:"events,omitempty"`
+	Errors         map[string][]*DevDocEntry `json:"errors,omitempty"`
+	StateVariables map[string]*DevDocEntry   `json:"stateVariables,omitempty"`
+}
+
+// DevDocEntry is the developer documentation of a method, event, error or variable
+type DevDocEntry struct {
+	Details string            `json:"details,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+	Returns map[string]string `json:"returns,omitempty"`
+}
+
+// UserDoc is the user documentation of a contract
+type UserDoc struct {
+	Kind    string                     `json:"kind"`
+	Version int                        `json:"version"`
+	Notice  string                     `json:"notice,omitempty"`
+	Methods map[string]*UserDocEntry   `json:"methods,omitempty"`
+	Events  map[string]*UserDocEntry   `json:"events,omitempty"`
+	Errors  map[string][]*UserDocEntry `json:"errors,omitempty"`
+}
+
+// UserDocEntry is the user documentation of a method, event or error
+type UserDocEntry struct {
+	Notice string `json:"notice,omitempty"`
+}
+
+// CodeRange is a range of bytes in the bytecode
+type CodeRange struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// FunctionDebugData is the debug information of a function in the bytecode
+type FunctionDebugData struct {
+	EntryPoint     *int `json:"entryPoint"`
+	ID             *int `json:"id"`
+	ParameterSlots int  `json:"parameterSlots"`
+	ReturnSlots    int  `json:"returnSlots"`
+}
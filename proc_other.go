@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package gosolc
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is not supported in this platform
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// killProcessGroup kills the process of the command. Its
+// children are not killed in this platform.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
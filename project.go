@@ -1,6 +1,7 @@
 package gosolc
 
 import (
+	"fmt"
 	"path/filepath"
 
 	"github.com/umbracle/gosolc/svm"
@@ -13,9 +14,19 @@ type Project struct {
 	// svm handles the lifecycle of the Solidity compiler binaries
 	svm *svm.SolidityVersionManager
 
+	// compiler returns the compiler for each solc version
+	compiler CompilerFactory
+
+	// remappings are the parsed import remappings of the config
+	remappings []*Remapping
+
 	sources []*Source
 
 	contracts contractsList
+
+	// statsUpdated is true if the modified time or size of any source
+	// changed since the cache was written
+	statsUpdated bool
 }
 
 type contractsList []*Contract
@@ -37,6 +48,11 @@ func NewProject(opts ...Option) (*Project, error) {
 
 	cfg.ContractsDir = filepath.Clean(cfg.ContractsDir)
 
+	// enable the optimizer with the runs unless it was set explicitly
+	if !cfg.optimizerSet {
+		cfg.Optimizer = cfg.Runs != 0
+	}
+
 	// default artifacts directory to the contracts directory if not set
 	if cfg.ArtifactsDir == "" {
 		cfg.ArtifactsDir = cfg.ContractsDir
@@ -44,18 +60,59 @@ func NewProject(opts ...Option) (*Project, error) {
 		cfg.ArtifactsDir = filepath.Clean(cfg.ArtifactsDir)
 	}
 
+	for indx, path := range cfg.IncludePaths {
+		cfg.IncludePaths[indx] = filepath.Clean(path)
+	}
+
+	// the remappings of the config take precedence over the ones in the file
+	fileRemappings, err := readRemappings(cfg.ContractsDir)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Remappings = append(fileRemappings, cfg.Remappings...)
+
+	remappings := []*Remapping{}
+	for _, str := range cfg.Remappings {
+		r, err := ParseRemapping(str)
+		if err != nil {
+			return nil, err
+		}
+		remappings = append(remappings, r)
+	}
+
+	for name, addr := range cfg.Libraries {
+		if _, _, err := splitLibraryName(name); err != nil {
+			return nil, err
+		}
+		if _, err := parseAddress(addr); err != nil {
+			return nil, fmt.Errorf("invalid address for library '%s': %v", name, err)
+		}
+	}
+
 	p := &Project{
-		config:    cfg,
-		sources:   []*Source{},
-		contracts: []*Contract{},
+		config:     cfg,
+		remappings: remappings,
+		sources:    []*Source{},
+		contracts:  []*Contract{},
 	}
 
-	svm, err := svm.NewSolidityVersionManager()
+	svm, err := svm.NewSolidityVersionManager(cfg.SVMOptions...)
 	if err != nil {
 		return nil, err
 	}
 	p.svm = svm
 
+	// restore the state of the previous compilations
+	if err := p.loadCache(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Compiler != nil {
+		p.compiler = cfg.Compiler
+	} else {
+		p.compiler = p.solcCompiler
+	}
+
 	return p, nil
 }
 
@@ -108,3 +165,41 @@ func (p *Project) UpsertSource(src *Source) error {
 	p.sources = append(p.sources, src)
 	return nil
 }
+
+// removeSource removes the source at path and its contracts from the project. The
+// sources that import it are compiled again. It returns the removed contracts.
+func (p *Project) removeSource(path string) []*Contract {
+	sources := []*Source{}
+	for _, s := range p.sources {
+		if s.relPath() == path {
+			continue
+		}
+		for _, imp := range s.Imports {
+			if imp == path {
+				s.invalidate()
+			}
+		}
+		sources = append(sources, s)
+	}
+	p.sources = sources
+
+	return p.removeContracts(func(c *Contract) bool {
+		return c.Source == path
+	})
+}
+
+// removeContracts removes the contracts that match the filter and returns them
+func (p *Project) removeContracts(filter func(c *Contract) bool) []*Contract {
+	removed := []*Contract{}
+	contracts := contractsList{}
+	for _, c := range p.contracts {
+		if filter(c) {
+			removed = append(removed, c)
+		} else {
+			contracts = append(contracts, c)
+		}
+	}
+	p.contracts = contracts
+
+	return removed
+}
@@ -18,7 +18,7 @@ func TestProject_Fixtures(t *testing.T) {
 		t.Run(e.Name(), func(t *testing.T) {
 			testPath := filepath.Join(fixturesPath, e.Name())
 
-			project, err := NewProject(WithContractsDir(testPath), WithRuns(200))
+			project, err := NewProject(WithContractsDir(testPath), WithArtifactsDir(t.TempDir()), WithRuns(200))
 			require.NoError(t, err)
 
 			res, err := project.Compile()
@@ -28,3 +28,24 @@ func TestProject_Fixtures(t *testing.T) {
 		})
 	}
 }
+
+func TestProject_Optimizer(t *testing.T) {
+	cases := []struct {
+		opts    []Option
+		enabled bool
+	}{
+		{nil, false},
+		{[]Option{WithRuns(200)}, true},
+		// the explicit setting does not depend on the order of the options
+		{[]Option{WithOptimizer(false), WithRuns(200)}, false},
+		{[]Option{WithRuns(200), WithOptimizer(false)}, false},
+		{[]Option{WithOptimizer(true), WithRuns(0)}, true},
+		{[]Option{WithRuns(0), WithOptimizer(true)}, true},
+	}
+
+	for _, c := range cases {
+		p, err := NewProject(append([]Option{WithContractsDir(t.TempDir())}, c.opts...)...)
+		require.NoError(t, err)
+		require.Equal(t, c.enabled, p.config.Optimizer)
+	}
+}
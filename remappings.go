@@ -0,0 +1,102 @@
+package gosolc
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const remappingsFile = "remappings.txt"
+
+// Remapping rewrites the imports that start with Prefix to start with Target
+// instead. If Context is set, it only applies to the sources inside that directory.
+type Remapping struct {
+	Context string
+	Prefix  string
+	Target  string
+}
+
+// ParseRemapping parses a remapping with the format [context:]prefix=target
+func ParseRemapping(str string) (*Remapping, error) {
+	eq := strings.Index(str, "=")
+	if eq == -1 {
+		return nil, fmt.Errorf("remapping '%s' has no '='", str)
+	}
+	prefix, target := str[:eq], str[eq+1:]
+
+	var context string
+	if indx := strings.Index(prefix, ":"); indx != -1 {
+		context, prefix = prefix[:indx], prefix[indx+1:]
+	}
+	if prefix == "" {
+		return nil, fmt.Errorf("remapping '%s' has an empty prefix", str)
+	}
+
+	r := &Remapping{
+		Context: context,
+		Prefix:  prefix,
+		Target:  target,
+	}
+	return r, nil
+}
+
+func (r *Remapping) String() string {
+	if r.Context != "" {
+		return r.Context + ":" + r.Prefix + "=" + r.Target
+	}
+	return r.Prefix + "=" + r.Target
+}
+
+// readRemappings reads the remappings of the remappings.txt file in dir.
+// It returns no remappings if the file does not exist.
+func readRemappings(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, remappingsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	res := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res = append(res, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// applyRemappings returns the source unit name of the import path imported from
+// the source unit importer. As in solc, the remapping with the longest context
+// is used and, among those, the one with the longest prefix. The last one wins a tie.
+func applyRemappings(remappings []*Remapping, importer, path string) string {
+	var match *Remapping
+	for _, r := range remappings {
+		if !strings.HasPrefix(importer, r.Context) || !strings.HasPrefix(path, r.Prefix) {
+			continue
+		}
+		if match != nil {
+			if len(r.Context) < len(match.Context) {
+				continue
+			}
+			if len(r.Context) == len(match.Context) && len(r.Prefix) < len(match.Prefix) {
+				continue
+			}
+		}
+		match = r
+	}
+	if match == nil {
+		return path
+	}
+	return match.Target + strings.TrimPrefix(path, match.Prefix)
+}
@@ -0,0 +1,98 @@
+package gosolc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRemapping(t *testing.T) {
+	cases := []struct {
+		str       string
+		remapping *Remapping
+	}{
+		{
+			"@openzeppelin/=node_modules/@openzeppelin/",
+			&Remapping{Prefix: "@openzeppelin/", Target: "node_modules/@openzeppelin/"},
+		},
+		{
+			"src/:ds-test/=lib/ds-test/src/",
+			&Remapping{Context: "src/", Prefix: "ds-test/", Target: "lib/ds-test/src/"},
+		},
+		{
+			"a=",
+			&Remapping{Prefix: "a"},
+		},
+		{
+			"node_modules",
+			nil,
+		},
+		{
+			"=lib",
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		r, err := ParseRemapping(c.str)
+		if c.remapping == nil {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, c.remapping, r)
+			require.Equal(t, c.str, r.String())
+		}
+	}
+}
+
+func TestApplyRemappings(t *testing.T) {
+	remappings := []*Remapping{}
+	for _, str := range []string{
+		"@oz/=lib/oz/",
+		"@oz/token/=lib/token/",
+		"test/:@oz/=lib/oz-test/",
+		"@a/=lib/a1/",
+		"@a/=lib/a2/",
+	} {
+		r, err := ParseRemapping(str)
+		require.NoError(t, err)
+		remappings = append(remappings, r)
+	}
+
+	cases := []struct {
+		importer string
+		path     string
+		result   string
+	}{
+		{"A.sol", "@oz/ERC20.sol", "lib/oz/ERC20.sol"},
+		// longest prefix
+		{"A.sol", "@oz/token/ERC20.sol", "lib/token/ERC20.sol"},
+		// longest context
+		{"test/A.sol", "@oz/token/ERC20.sol", "lib/oz-test/token/ERC20.sol"},
+		// the last one wins
+		{"A.sol", "@a/A.sol", "lib/a2/A.sol"},
+		// no remapping
+		{"A.sol", "@b/B.sol", "@b/B.sol"},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.result, applyRemappings(remappings, c.importer, c.path))
+	}
+}
+
+func TestReadRemappings(t *testing.T) {
+	dir := t.TempDir()
+
+	remappings, err := readRemappings(dir)
+	require.NoError(t, err)
+	require.Empty(t, remappings)
+
+	content := "@oz/=lib/oz/\n\n# comment\n  ds-test/=lib/ds-test/src/  \n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, remappingsFile), []byte(content), 0644))
+
+	remappings, err = readRemappings(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"@oz/=lib/oz/", "ds-test/=lib/ds-test/src/"}, remappings)
+}
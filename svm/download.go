@@ -0,0 +1,165 @@
+package svm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRetries = 3
+	defaultBackoff = time.Second
+)
+
+// ErrUnknownVersion is returned when the requested compiler version does not exist
+var ErrUnknownVersion = errors.New("unknown solc version")
+
+// TransientError is returned when a download fails with an error that
+// might succeed if retried (i.e. network errors or 5xx responses)
+type TransientError struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *TransientError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("failed to download '%s': %v", e.URL, e.Err)
+	}
+	return fmt.Sprintf("failed to download '%s': status %d", e.URL, e.StatusCode)
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// downloader is an http client that retries the transient errors with an
+// exponential backoff and resumes the interrupted downloads with range requests
+type downloader struct {
+	retries int
+	backoff time.Duration
+}
+
+// get performs a single request for the content of url starting at offset
+func (d *downloader) get(ctx context.Context, url string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset != 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &TransientError{URL: url, Err: err}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent:
+		return resp, nil
+
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		resp.Body.Close()
+		return nil, fmt.Errorf("'%s': %w", url, ErrUnknownVersion)
+
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		resp.Body.Close()
+		return nil, &TransientError{URL: url, StatusCode: resp.StatusCode}
+
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download '%s': unexpected status %s", url, resp.Status)
+	}
+}
+
+// getWithRetries calls get until it succeeds, fails with a non transient error
+// or runs out of retries
+func (d *downloader) getWithRetries(ctx context.Context, url string, offset int64) (*http.Response, error) {
+	backoff := d.backoff
+
+	for attempt := 0; ; attempt++ {
+		resp, err := d.get(ctx, url, offset)
+		if err == nil {
+			return resp, nil
+		}
+
+		var transientErr *TransientError
+		if !errors.As(err, &transientErr) || attempt >= d.retries {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// fetch returns the body of url and its size (-1 if unknown). Reading
+// the body resumes the download if the connection is interrupted.
+func (d *downloader) fetch(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+	resp, err := d.getWithRetries(ctx, url, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	body := &resumableBody{
+		ctx:  ctx,
+		d:    d,
+		url:  url,
+		body: resp.Body,
+	}
+	return body, resp.ContentLength, nil
+}
+
+type resumableBody struct {
+	ctx     context.Context
+	d       *downloader
+	url     string
+	body    io.ReadCloser
+	offset  int64
+	resumes int
+}
+
+func (r *resumableBody) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+
+	if err == nil || err == io.EOF || r.ctx.Err() != nil {
+		return n, err
+	}
+	if r.resumes >= r.d.retries {
+		return n, &TransientError{URL: r.url, Err: err}
+	}
+	r.resumes++
+
+	// the connection was interrupted, request the rest of the content
+	r.body.Close()
+
+	resp, rErr := r.d.getWithRetries(r.ctx, r.url, r.offset)
+	if rErr != nil {
+		return n, rErr
+	}
+	if resp.StatusCode == http.StatusOK {
+		// the server does not support ranges, skip the bytes already read
+		if _, err := io.CopyN(io.Discard, resp.Body, r.offset); err != nil {
+			resp.Body.Close()
+			return n, err
+		}
+	}
+	r.body = resp.Body
+
+	return n, nil
+}
+
+func (r *resumableBody) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,113 @@
+package svm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testDownloader() *downloader {
+	return &downloader{retries: 2, backoff: time.Millisecond}
+}
+
+func TestDownload_Status(t *testing.T) {
+	var requests int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+
+		status, _ := strconv.Atoi(r.URL.Path[1:])
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		status    int
+		transient bool
+		requests  int64
+	}{
+		{http.StatusNotFound, false, 1},
+		{http.StatusForbidden, false, 1},
+		{http.StatusServiceUnavailable, true, 3},
+		{http.StatusTooManyRequests, true, 3},
+	}
+
+	for _, c := range cases {
+		atomic.StoreInt64(&requests, 0)
+
+		_, _, err := testDownloader().fetch(context.Background(), srv.URL+"/"+strconv.Itoa(c.status))
+		require.Error(t, err)
+
+		var transientErr *TransientError
+		require.Equal(t, c.transient, errors.As(err, &transientErr))
+		if c.transient {
+			require.Equal(t, c.status, transientErr.StatusCode)
+		}
+		if c.status == http.StatusNotFound {
+			require.ErrorIs(t, err, ErrUnknownVersion)
+		}
+		require.Equal(t, c.requests, atomic.LoadInt64(&requests))
+	}
+}
+
+func TestDownload_Retry(t *testing.T) {
+	var requests int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("binary"))
+	}))
+	defer srv.Close()
+
+	body, _, err := testDownloader().fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "binary", string(data))
+	require.Equal(t, int64(3), atomic.LoadInt64(&requests))
+}
+
+func TestDownload_Resume(t *testing.T) {
+	binary := bytes.Repeat([]byte("solc"), 1024)
+
+	ranges := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+
+		if len(ranges) == 1 {
+			// write half of the binary and abort the connection
+			w.Header().Set("Content-Length", strconv.Itoa(len(binary)))
+			w.Write(binary[:len(binary)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "solc", time.Time{}, bytes.NewReader(binary))
+	}))
+	defer srv.Close()
+
+	body, size, err := testDownloader().fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer body.Close()
+
+	require.Equal(t, int64(len(binary)), size)
+
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, binary, data)
+
+	require.Len(t, ranges, 2)
+	require.Equal(t, "", ranges[0])
+	require.Equal(t, "bytes="+strconv.Itoa(len(binary)/2)+"-", ranges[1])
+}
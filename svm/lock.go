@@ -0,0 +1,96 @@
+package svm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	// lockPollInterval is how often a held lock file is checked
+	lockPollInterval = 100 * time.Millisecond
+
+	// lockRefreshInterval is how often the holder updates the mtime of the lock file
+	lockRefreshInterval = 10 * time.Second
+
+	// staleLockTimeout is the age of the mtime after which a lock file is
+	// considered abandoned (i.e. the holder crashed) and it is removed
+	staleLockTimeout = time.Minute
+)
+
+// lockFileExclusive acquires a lock by creating the file at path exclusively and
+// blocks until it is available. The holder refreshes the mtime of the file until
+// it releases the lock so that the lock of a process that died is taken over once
+// it is older than staleLockTimeout.
+func lockFileExclusive(path string) (func() error, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		info, err := os.Stat(path)
+		if err == nil && time.Since(info.ModTime()) > staleLockTimeout {
+			if err := removeStaleLock(path, info); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	doneCh := make(chan struct{})
+	ticker := time.NewTicker(lockRefreshInterval)
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				os.Chtimes(path, now, now)
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	unlock := func() error {
+		close(doneCh)
+		return os.Remove(path)
+	}
+	return unlock, nil
+}
+
+// removeStaleLock removes the stale lock file at path. Another waiter might have
+// already replaced it with its own lock, so the file is first moved atomically to
+// a unique name and only removed if it is still the stale file seen before.
+// Otherwise, it is moved back.
+func removeStaleLock(path string, stale os.FileInfo) error {
+	tmp := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, tmp); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// taken over by another waiter
+			return nil
+		}
+		return err
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(stale.ModTime()) {
+		return os.Remove(tmp)
+	}
+
+	// the lock is held by another waiter. The link fails if the path exists.
+	if err := os.Link(tmp, path); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return os.Remove(tmp)
+}
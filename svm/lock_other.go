@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package svm
+
+// lockFile acquires an exclusive lock on the file at path and blocks
+// until the lock is available. It returns a function to release the lock.
+// On platforms without flock the lock is held by creating the file exclusively.
+func lockFile(path string) (func() error, error) {
+	return lockFileExclusive(path)
+}
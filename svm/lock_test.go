@@ -0,0 +1,156 @@
+package svm
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFileExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	unlock, err := lockFileExclusive(path)
+	require.NoError(t, err)
+
+	// the lock blocks until it is released
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := lockFileExclusive(path)
+		require.NoError(t, err)
+		close(acquired)
+		unlock()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(3 * lockPollInterval):
+	}
+
+	require.NoError(t, unlock())
+	<-acquired
+}
+
+func TestLockFileExclusive_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	// the lock file of a process that died
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	old := time.Now().Add(-2 * staleLockTimeout)
+	require.NoError(t, os.Chtimes(path, old, old))
+
+	unlock, err := lockFileExclusive(path)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLockFileExclusive_Refresh(t *testing.T) {
+	defer func(refresh, timeout time.Duration) {
+		lockRefreshInterval, staleLockTimeout = refresh, timeout
+	}(lockRefreshInterval, staleLockTimeout)
+	lockRefreshInterval, staleLockTimeout = 50*time.Millisecond, 300*time.Millisecond
+
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	unlock, err := lockFileExclusive(path)
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := lockFileExclusive(path)
+		require.NoError(t, err)
+		close(acquired)
+		unlock()
+	}()
+
+	// the lock is not stale while the holder is alive
+	select {
+	case <-acquired:
+		t.Fatal("held lock taken over as stale")
+	case <-time.After(3 * staleLockTimeout):
+	}
+
+	require.NoError(t, unlock())
+	<-acquired
+}
+
+func TestLockFileExclusive_StaleConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".lock")
+
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	old := time.Now().Add(-2 * staleLockTimeout)
+	require.NoError(t, os.Chtimes(path, old, old))
+
+	// the waiters take over the stale lock one at a time
+	var holders, maxHolders int32
+
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			<-start
+			unlock, err := lockFileExclusive(path)
+			require.NoError(t, err)
+
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				max := atomic.LoadInt32(&maxHolders)
+				if n <= max || atomic.CompareAndSwapInt32(&maxHolders, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+
+			require.NoError(t, unlock())
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	require.Equal(t, int32(1), maxHolders)
+
+	// the stale lock files are cleaned up
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestRemoveStaleLock_TakenOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	old := time.Now().Add(-2 * staleLockTimeout)
+	require.NoError(t, os.Chtimes(path, old, old))
+
+	stale, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// another waiter takes over the stale lock first
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.WriteFile(path, []byte("holder"), 0644))
+
+	// and its lock is kept
+	require.NoError(t, removeStaleLock(path, stale))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "holder", string(data))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
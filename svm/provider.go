@@ -0,0 +1,295 @@
+package svm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// Provider is a source of compiler binaries
+type Provider interface {
+	// Versions returns the compiler versions available in the provider
+	Versions(ctx context.Context) ([]*version.Version, error)
+
+	// Fetch returns the compiler binary for the version
+	Fetch(ctx context.Context, v *version.Version) (*Binary, error)
+}
+
+// Binary is a compiler binary returned by a provider
+type Binary struct {
+	// Body is the content of the binary
+	Body io.ReadCloser
+
+	// Size is the size of the binary or -1 if unknown
+	Size int64
+
+	// Build is the release list entry used to verify the checksums
+	// of the binary. It is nil if the provider does not publish checksums.
+	Build *Build
+}
+
+// GithubProvider downloads the static linux binaries from the
+// Solidity GitHub releases and verifies them with the release list
+type GithubProvider struct {
+	// Retries is the number of times a failed download is retried
+	Retries int
+
+	// Backoff is the delay before the first retry. It doubles on each retry.
+	Backoff time.Duration
+
+	baseURL  string
+	releases *releaseListCache
+}
+
+// NewGithubProvider creates a provider for the GitHub releases hosted at baseURL
+// that verifies the binaries with the release list hosted at manifestURL
+func NewGithubProvider(baseURL, manifestURL string) *GithubProvider {
+	return &GithubProvider{
+		Retries:  defaultRetries,
+		Backoff:  defaultBackoff,
+		baseURL:  baseURL,
+		releases: &releaseListCache{url: manifestURL},
+	}
+}
+
+func (g *GithubProvider) downloader() *downloader {
+	return &downloader{retries: g.Retries, backoff: g.Backoff}
+}
+
+func (g *GithubProvider) Versions(ctx context.Context) ([]*version.Version, error) {
+	releases, err := g.releases.get(ctx, g.downloader())
+	if err != nil {
+		return nil, err
+	}
+	return releases.Versions()
+}
+
+func (g *GithubProvider) Fetch(ctx context.Context, v *version.Version) (*Binary, error) {
+	releases, err := g.releases.get(ctx, g.downloader())
+	if err != nil {
+		return nil, err
+	}
+	build, ok := releases.Release(v.String())
+	if !ok {
+		return nil, fmt.Errorf("solc %s not found in the release list: %w", v.String(), ErrUnknownVersion)
+	}
+
+	url := strings.TrimSuffix(g.baseURL, "/") + "/v" + v.String() + "/solc-static-linux"
+	return httpFetch(ctx, g.downloader(), url, build)
+}
+
+// BinariesProvider downloads the binaries listed in a solc-bin release list
+// (i.e. https://binaries.soliditylang.org/linux-amd64). The platform is chosen
+// with the url of the list. Only native builds are supported since the compiler
+// is run as an executable, the solc-js (wasm) builds cannot be used.
+type BinariesProvider struct {
+	// Retries is the number of times a failed download is retried
+	Retries int
+
+	// Backoff is the delay before the first retry. It doubles on each retry.
+	Backoff time.Duration
+
+	url      string
+	releases *releaseListCache
+}
+
+// NewBinariesProvider creates a provider for the solc-bin list hosted at url
+func NewBinariesProvider(url string) *BinariesProvider {
+	return &BinariesProvider{
+		Retries:  defaultRetries,
+		Backoff:  defaultBackoff,
+		url:      url,
+		releases: &releaseListCache{url: url},
+	}
+}
+
+func (b *BinariesProvider) downloader() *downloader {
+	return &downloader{retries: b.Retries, backoff: b.Backoff}
+}
+
+func (b *BinariesProvider) Versions(ctx context.Context) ([]*version.Version, error) {
+	releases, err := b.releases.get(ctx, b.downloader())
+	if err != nil {
+		return nil, err
+	}
+	return releases.Versions()
+}
+
+func (b *BinariesProvider) Fetch(ctx context.Context, v *version.Version) (*Binary, error) {
+	releases, err := b.releases.get(ctx, b.downloader())
+	if err != nil {
+		return nil, err
+	}
+	build, ok := releases.Release(v.String())
+	if !ok {
+		return nil, fmt.Errorf("solc %s not found in the release list: %w", v.String(), ErrUnknownVersion)
+	}
+
+	url := strings.TrimSuffix(b.url, "/") + "/" + build.Path
+	return httpFetch(ctx, b.downloader(), url, build)
+}
+
+func httpFetch(ctx context.Context, d *downloader, url string, build *Build) (*Binary, error) {
+	body, size, err := d.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &Binary{Body: body, Size: size, Build: build}, nil
+}
+
+func openFile(path string) (*Binary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Binary{Body: f, Size: info.Size()}, nil
+}
+
+var localBinaryRegexp = regexp.MustCompile(`^(?:solc|solidity)[-_](?:[a-z0-9]+-[a-z0-9]+-)?v?(\d+\.\d+\.\d+)(?:\+commit\.[0-9a-f]+)?(?:\.exe)?$`)
+
+// LocalProvider uses the prebuilt binaries of a local directory. The binaries are
+// expected to be named solc-<version>, solidity-<version> or as in solc-bin
+// (i.e. solc-linux-amd64-v0.8.0+commit.c7dfd78e).
+type LocalProvider struct {
+	dir string
+}
+
+// NewLocalProvider creates a provider for the binaries in dir
+func NewLocalProvider(dir string) *LocalProvider {
+	return &LocalProvider{
+		dir: dir,
+	}
+}
+
+func (l *LocalProvider) binaries() (map[string]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := localBinaryRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		res[match[1]] = filepath.Join(l.dir, entry.Name())
+	}
+	return res, nil
+}
+
+func (l *LocalProvider) Versions(ctx context.Context) ([]*version.Version, error) {
+	binaries, err := l.binaries()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*version.Version{}
+	for v := range binaries {
+		vv, err := version.NewVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, vv)
+	}
+	return res, nil
+}
+
+func (l *LocalProvider) Fetch(ctx context.Context, v *version.Version) (*Binary, error) {
+	binaries, err := l.binaries()
+	if err != nil {
+		return nil, err
+	}
+	path, ok := binaries[v.String()]
+	if !ok {
+		return nil, fmt.Errorf("solc %s not found in '%s': %w", v.String(), l.dir, ErrUnknownVersion)
+	}
+
+	return openFile(path)
+}
+
+// SystemProvider uses the solc compiler available in the PATH
+type SystemProvider struct {
+	name string
+}
+
+// NewSystemProvider creates a provider for the solc binary in the PATH
+func NewSystemProvider() *SystemProvider {
+	return &SystemProvider{
+		name: "solc",
+	}
+}
+
+func (s *SystemProvider) binary(ctx context.Context) (string, *version.Version, error) {
+	path, err := exec.LookPath(s.name)
+	if err != nil {
+		return "", nil, err
+	}
+	v, _, err := binaryVersion(ctx, path)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, v, nil
+}
+
+func (s *SystemProvider) Versions(ctx context.Context) ([]*version.Version, error) {
+	_, v, err := s.binary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []*version.Version{v}, nil
+}
+
+func (s *SystemProvider) Fetch(ctx context.Context, v *version.Version) (*Binary, error) {
+	path, systemVersion, err := s.binary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !systemVersion.Equal(v) {
+		return nil, fmt.Errorf("system solc is %s, not %s", systemVersion.String(), v.String())
+	}
+
+	return openFile(path)
+}
+
+var versionOutputRegexp = regexp.MustCompile(`Version: (\d+\.\d+\.\d+)(?:[-+]\S*?commit\.([0-9a-f]+))?`)
+
+// binaryVersion runs the compiler with --version and returns
+// the reported version and commit
+func binaryVersion(ctx context.Context, path string) (*version.Version, string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "--version")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("failed to run '%s --version': %v %s", path, err, stderr.String())
+	}
+
+	match := versionOutputRegexp.FindStringSubmatch(stdout.String())
+	if match == nil {
+		return nil, "", fmt.Errorf("failed to parse the version of '%s'", path)
+	}
+	v, err := version.NewVersion(match[1])
+	if err != nil {
+		return nil, "", err
+	}
+	return v, match[2], nil
+}
@@ -0,0 +1,157 @@
+package svm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+)
+
+func versionsStr(versions []*version.Version) []string {
+	sort.Sort(version.Collection(versions))
+
+	res := []string{}
+	for _, v := range versions {
+		res = append(res, v.String())
+	}
+	return res
+}
+
+func fetchStr(t *testing.T, p Provider, v string) string {
+	t.Helper()
+
+	bin, err := p.Fetch(context.Background(), version.Must(version.NewVersion(v)))
+	require.NoError(t, err)
+	defer bin.Body.Close()
+
+	data, err := io.ReadAll(bin.Body)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// writeFakeSolc writes an executable that reports the given version
+func writeFakeSolc(t *testing.T, path string, version string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, fakeSolc(version), 0755))
+}
+
+func TestProvider_Binaries(t *testing.T) {
+	binary := []byte("solc 0.8.0")
+	list := testReleaseList(t, "0.8.0", binary)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/linux-amd64/list.json":
+			json.NewEncoder(w).Encode(list)
+		case "/linux-amd64/" + list.Builds[0].Path:
+			w.Write(binary)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewBinariesProvider(srv.URL + "/linux-amd64")
+
+	versions, err := p.Versions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.8.0"}, versionsStr(versions))
+
+	require.Equal(t, "solc 0.8.0", fetchStr(t, p, "0.8.0"))
+}
+
+func TestProvider_Local(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"solc-0.8.0":                          "a",
+		"solidity-0.8.1":                      "b",
+		"solc-v0.8.2":                         "c",
+		"solc-linux-amd64-v0.8.3+commit.abcd": "d",
+		"README.md":                           "",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0755))
+	}
+
+	p := NewLocalProvider(dir)
+
+	versions, err := p.Versions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.8.0", "0.8.1", "0.8.2", "0.8.3"}, versionsStr(versions))
+
+	require.Equal(t, "c", fetchStr(t, p, "0.8.2"))
+	require.Equal(t, "d", fetchStr(t, p, "0.8.3"))
+
+	_, err = p.Fetch(context.Background(), version.Must(version.NewVersion("0.8.4")))
+	require.Error(t, err)
+}
+
+func TestProvider_System(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeSolc(t, filepath.Join(dir, "solc"), "0.8.7")
+
+	t.Setenv("PATH", dir)
+
+	p := NewSystemProvider()
+
+	versions, err := p.Versions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.8.7"}, versionsStr(versions))
+
+	_, err = p.Fetch(context.Background(), version.Must(version.NewVersion("0.8.6")))
+	require.Error(t, err)
+}
+
+func TestSVM_Providers(t *testing.T) {
+	localDir := t.TempDir()
+	writeFakeSolc(t, filepath.Join(localDir, "solc-0.8.0"), "0.8.0")
+
+	svm, err := NewSolidityVersionManager(WithDir(t.TempDir()), WithProviders(NewLocalProvider(localDir)))
+	require.NoError(t, err)
+
+	path, err := svm.Resolve("0.8.0")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, fakeSolc("0.8.0"), data)
+
+	_, err = svm.Resolve("0.8.1")
+	require.Error(t, err)
+
+	_, v, err := svm.ResolveConstraint(version.MustConstraints(version.NewConstraint(">= 0.7.0")))
+	require.NoError(t, err)
+	require.Equal(t, "0.8.0", v.String())
+}
+
+func TestSVM_ProvidersFailure(t *testing.T) {
+	// there is no solc in the PATH for the system provider
+	t.Setenv("PATH", t.TempDir())
+
+	localDir := t.TempDir()
+	writeFakeSolc(t, filepath.Join(localDir, "solc-0.8.0"), "0.8.0")
+
+	svm, err := NewSolidityVersionManager(WithDir(t.TempDir()), WithProviders(NewSystemProvider(), NewLocalProvider(localDir)))
+	require.NoError(t, err)
+
+	_, v, err := svm.ResolveConstraint(version.MustConstraints(version.NewConstraint(">= 0.7.0")))
+	require.NoError(t, err)
+	require.Equal(t, "0.8.0", v.String())
+
+	_, err = svm.Resolve("0.8.1")
+	require.ErrorIs(t, err, ErrUnknownVersion)
+	require.Contains(t, err.Error(), "provider errors")
+
+	_, _, err = svm.ResolveConstraint(version.MustConstraints(version.NewConstraint(">= 0.9.0")))
+	require.ErrorIs(t, err, ErrNoMatchingVersion)
+	require.Contains(t, err.Error(), "provider errors")
+}
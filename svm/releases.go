@@ -0,0 +1,128 @@
+package svm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	version "github.com/hashicorp/go-version"
+)
+
+const defaultManifestURL = "https://binaries.soliditylang.org/linux-amd64"
+
+// ReleaseList is the list.json manifest published along with the solc binaries
+type ReleaseList struct {
+	// Builds is the list of all the compiler builds
+	Builds []*Build `json:"builds"`
+
+	// Releases maps each release version to the path of its build
+	Releases map[string]string `json:"releases"`
+
+	// LatestRelease is the version of the latest release
+	LatestRelease string `json:"latestRelease"`
+}
+
+// Build is a compiler build in the release list
+type Build struct {
+	Path        string   `json:"path"`
+	Version     string   `json:"version"`
+	Build       string   `json:"build"`
+	LongVersion string   `json:"longVersion"`
+	Keccak256   string   `json:"keccak256"`
+	Sha256      string   `json:"sha256"`
+	URLs        []string `json:"urls"`
+}
+
+// Release returns the build for the release with the given version
+func (r *ReleaseList) Release(version string) (*Build, bool) {
+	path, ok := r.Releases[version]
+	if !ok {
+		return nil, false
+	}
+	for _, b := range r.Builds {
+		if b.Path == path {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// Versions returns the versions of all the releases
+func (r *ReleaseList) Versions() ([]*version.Version, error) {
+	res := []*version.Version{}
+	for release := range r.Releases {
+		v, err := version.NewVersion(release)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse release version '%s': %v", release, err)
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// releaseListCache fetches the release list only once
+type releaseListCache struct {
+	url string
+
+	lock sync.Mutex
+	list *ReleaseList
+}
+
+func (r *releaseListCache) get(ctx context.Context, d *downloader) (*ReleaseList, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.list != nil {
+		return r.list, nil
+	}
+	list, err := fetchReleaseList(ctx, d, r.url)
+	if err != nil {
+		return nil, err
+	}
+	r.list = list
+	return list, nil
+}
+
+// cached returns the release list if it was already fetched or nil otherwise
+func (r *releaseListCache) cached() *ReleaseList {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.list
+}
+
+func fetchReleaseList(ctx context.Context, d *downloader, baseURL string) (*ReleaseList, error) {
+	url := strings.TrimSuffix(baseURL, "/") + "/list.json"
+
+	body, _, err := d.fetch(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release list: %w", err)
+	}
+	defer body.Close()
+
+	var list *ReleaseList
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode release list: %v", err)
+	}
+	return list, nil
+}
+
+// ChecksumError is returned when a downloaded binary does not match
+// the checksum published in the release list
+type ChecksumError struct {
+	Version   string
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch for solc %s: expected %s but got %s", e.Algorithm, e.Version, e.Expected, e.Actual)
+}
+
+// normalizeHash removes the 0x prefix from a hex encoded hash
+func normalizeHash(hash string) string {
+	return strings.TrimPrefix(strings.ToLower(hash), "0x")
+}
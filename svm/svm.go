@@ -1,21 +1,53 @@
 package svm
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	"golang.org/x/crypto/sha3"
+	"golang.org/x/sync/singleflight"
 )
 
+const defaultBaseURL = "https://github.com/ethereum/solidity/releases/download"
+
+// ErrVersionNotInstalled is returned in offline mode when the requested
+// compiler version is not installed
+var ErrVersionNotInstalled = errors.New("solc version not installed")
+
+// ErrNoMatchingVersion is returned when there is no compiler release that
+// satisfies a version constraint
+var ErrNoMatchingVersion = errors.New("no solc release matches the constraint")
+
 type config struct {
-	logger *log.Logger
-	dir    string
+	logger      *log.Logger
+	dir         string
+	baseURL     string
+	manifestURL string
+	offline     bool
+	providers   []Provider
+	progress    ProgressFunc
+	retries     int
+	backoff     time.Duration
 }
 
+// ProgressFunc is called while a compiler is downloaded with the number of
+// bytes received and the total size of the binary (-1 if unknown)
+type ProgressFunc func(version string, received, total int64)
+
 type Option func(*config)
 
 func WithLogger(logger *log.Logger) Option {
@@ -30,15 +62,75 @@ func WithDir(dir string) Option {
 	}
 }
 
+// WithBaseURL sets the base url of the default provider to download the compiler
+// releases from. The binary for each version is expected at <url>/v<version>/solc-static-linux
+func WithBaseURL(url string) Option {
+	return func(c *config) {
+		c.baseURL = url
+	}
+}
+
+// WithOffline disables any network access. Only the compilers already
+// installed can be resolved
+func WithOffline(offline bool) Option {
+	return func(c *config) {
+		c.offline = offline
+	}
+}
+
+// WithManifestURL sets the base url of the list.json release manifest
+// used to verify the downloaded binaries
+func WithManifestURL(url string) Option {
+	return func(c *config) {
+		c.manifestURL = url
+	}
+}
+
+// WithProviders sets the providers used to install the compilers. The providers
+// are checked in order. By default, the binaries are downloaded from the GitHub
+// releases at the base url.
+func WithProviders(providers ...Provider) Option {
+	return func(c *config) {
+		c.providers = providers
+	}
+}
+
+// WithRetries sets the number of times the default provider retries a failed
+// download and the delay before the first retry, which doubles on each retry
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *config) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithProgress sets a callback to report the progress of the downloads
+func WithProgress(progress ProgressFunc) Option {
+	return func(c *config) {
+		c.progress = progress
+	}
+}
+
 // SolidityVersionManager is a service to manage solidity compiler versions
 type SolidityVersionManager struct {
 	config *config
+
+	releases *releaseListCache
+
+	installGroup singleflight.Group
+
+	verifiedLock sync.Mutex
+	verified     map[string]*verifyResult
 }
 
 // NewSolidityVersionManager creates a new Solidity Version Manager
 func NewSolidityVersionManager(opts ...Option) (*SolidityVersionManager, error) {
 	cfg := &config{
-		logger: log.New(ioutil.Discard, "", 0),
+		logger:      log.New(ioutil.Discard, "", 0),
+		baseURL:     defaultBaseURL,
+		manifestURL: defaultManifestURL,
+		retries:     defaultRetries,
+		backoff:     defaultBackoff,
 	}
 	for _, opt := range opts {
 		opt(cfg)
@@ -54,21 +146,47 @@ func NewSolidityVersionManager(opts ...Option) (*SolidityVersionManager, error)
 	}
 
 	s := &SolidityVersionManager{
-		config: cfg,
+		config:   cfg,
+		releases: &releaseListCache{url: cfg.manifestURL},
+		verified: map[string]*verifyResult{},
+	}
+	if len(cfg.providers) == 0 {
+		s.config.providers = []Provider{
+			&GithubProvider{
+				Retries:  cfg.retries,
+				Backoff:  cfg.backoff,
+				baseURL:  cfg.baseURL,
+				releases: s.releases,
+			},
+		}
 	}
 	return s, nil
 }
 
-// Resolve returns the path for the compiler and downloads it if necessary
+const binaryPrefix = "solidity-"
+
+func (s *SolidityVersionManager) path(version string) string {
+	return filepath.Join(s.config.dir, binaryPrefix+version)
+}
+
+// Resolve returns the path for the compiler and downloads it if necessary.
+// The binary is verified by running it with --version before it is returned.
+// It is safe to call Resolve concurrently, a version is only downloaded once.
 func (s *SolidityVersionManager) Resolve(version string) (string, error) {
-	path := filepath.Join(s.config.dir, "solidity-"+version)
+	return s.ResolveContext(context.Background(), version)
+}
+
+// ResolveContext is like Resolve but the download is aborted if the context is done
+func (s *SolidityVersionManager) ResolveContext(ctx context.Context, version string) (string, error) {
+	path := s.path(version)
 
 	if _, err := os.Stat(path); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			s.config.logger.Printf("[INFO]: Downloading solc compiler (%s)...\n", version)
+			if s.config.offline {
+				return "", fmt.Errorf("solc %s: %w", version, ErrVersionNotInstalled)
+			}
 
-			// download the compiler
-			if err := downloadSolidity(version, s.config.dir); err != nil {
+			if err := s.installShared(ctx, version); err != nil {
 				return "", err
 			}
 		} else {
@@ -77,11 +195,293 @@ func (s *SolidityVersionManager) Resolve(version string) (string, error) {
 		}
 	}
 
+	if err := s.verify(ctx, version, path, false); err != nil {
+		return "", err
+	}
 	return path, nil
 }
 
-func downloadSolidity(version string, dst string) error {
-	url := "https://github.com/ethereum/solidity/releases/download/v" + version + "/solc-static-linux"
+// installShared deduplicates concurrent installs of the same version. The
+// download is bound to the context of the caller that starts it, so if that
+// caller gives up, the others start the download again with their own context.
+func (s *SolidityVersionManager) installShared(ctx context.Context, version string) error {
+	for {
+		ch := s.installGroup.DoChan(version, func() (interface{}, error) {
+			return nil, s.install(ctx, version)
+		})
+		select {
+		case res := <-ch:
+			if res.Err == nil {
+				return nil
+			}
+			if ctx.Err() == nil && isContextErr(res.Err) {
+				continue
+			}
+			return res.Err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// install downloads the compiler while holding a lock in the svm directory
+// so that other processes do not download the same version concurrently.
+func (s *SolidityVersionManager) install(ctx context.Context, ver string) error {
+	if err := os.MkdirAll(s.config.dir, 0755); err != nil {
+		return fmt.Errorf("cannot create dst path: %v", err)
+	}
+
+	unlock, err := lockFile(filepath.Join(s.config.dir, ".lock-"+ver))
+	if err != nil {
+		return fmt.Errorf("failed to lock svm directory: %v", err)
+	}
+	defer unlock()
+
+	// the compiler might have been installed while waiting for the lock
+	if _, err := os.Stat(s.path(ver)); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.fetch(ctx, ver, s.config.dir)
+}
+
+// reinstall downloads the compiler again into a temporary directory and replaces
+// the installed binary only if the new one is valid. The installed binary is
+// kept if the download or the verification fail.
+func (s *SolidityVersionManager) reinstall(ctx context.Context, ver string) error {
+	unlock, err := lockFile(filepath.Join(s.config.dir, ".lock-"+ver))
+	if err != nil {
+		return fmt.Errorf("failed to lock svm directory: %v", err)
+	}
+	defer unlock()
+
+	tmpDir, err := ioutil.TempDir(s.config.dir, "solc-reinstall-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := s.fetch(ctx, ver, tmpDir); err != nil {
+		return err
+	}
+	path := filepath.Join(tmpDir, binaryPrefix+ver)
+	if err := s.verifyBinary(ctx, ver, path); err != nil {
+		return err
+	}
+	return os.Rename(path, s.path(ver))
+}
+
+// fetch downloads the compiler from the first provider that has it into dst
+func (s *SolidityVersionManager) fetch(ctx context.Context, ver string, dst string) error {
+	v, err := version.NewVersion(ver)
+	if err != nil {
+		return err
+	}
+
+	var errs providerErrors
+	for _, provider := range s.config.providers {
+		available, err := provider.Versions(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			// try the next provider
+			errs = append(errs, err)
+			continue
+		}
+		if !containsVersion(available, v) {
+			continue
+		}
+
+		s.config.logger.Printf("[INFO]: Downloading solc compiler (%s)...\n", ver)
+
+		bin, err := provider.Fetch(ctx, v)
+		if err != nil {
+			return err
+		}
+		return installBinary(ctx, bin, ver, dst, s.config.progress)
+	}
+	return fmt.Errorf("solc %s not found in any provider: %w%s", ver, ErrUnknownVersion, errs)
+}
+
+// ResolveConstraint returns the path and the version of the newest compiler that
+// satisfies the constraint. The installed compilers are checked first and the
+// providers are only used if none of them matches.
+func (s *SolidityVersionManager) ResolveConstraint(constraint version.Constraints) (string, *version.Version, error) {
+	return s.ResolveConstraintContext(context.Background(), constraint)
+}
+
+// ResolveConstraintContext is like ResolveConstraint but the download is aborted
+// if the context is done
+func (s *SolidityVersionManager) ResolveConstraintContext(ctx context.Context, constraint version.Constraints) (string, *version.Version, error) {
+	installed, err := s.Installed()
+	if err != nil {
+		return "", nil, err
+	}
+	if v := newestMatch(installed, constraint); v != nil {
+		path, err := s.ResolveContext(ctx, v.String())
+		if err != nil {
+			return "", nil, err
+		}
+		return path, v, nil
+	}
+
+	if s.config.offline {
+		return "", nil, fmt.Errorf("solc '%s': %w", constraint.String(), ErrVersionNotInstalled)
+	}
+
+	var errs providerErrors
+	available := []*version.Version{}
+	for _, provider := range s.config.providers {
+		versions, err := provider.Versions(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", nil, err
+			}
+			// try the next provider
+			errs = append(errs, err)
+			continue
+		}
+		available = append(available, versions...)
+	}
+
+	v := newestMatch(available, constraint)
+	if v == nil {
+		return "", nil, fmt.Errorf("solc '%s': %w%s", constraint.String(), ErrNoMatchingVersion, errs)
+	}
+
+	path, err := s.ResolveContext(ctx, v.String())
+	if err != nil {
+		return "", nil, err
+	}
+	return path, v, nil
+}
+
+// providerErrors are the errors of the providers that failed to list their versions.
+// They are appended to the error returned when no provider has the version.
+type providerErrors []error
+
+func (e providerErrors) String() string {
+	if len(e) == 0 {
+		return ""
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return " (provider errors: " + strings.Join(msgs, "; ") + ")"
+}
+
+func containsVersion(versions []*version.Version, v *version.Version) bool {
+	for _, vv := range versions {
+		if vv.Equal(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// newestMatch returns the newest version that satisfies the constraint or nil
+func newestMatch(versions []*version.Version, constraint version.Constraints) *version.Version {
+	var res *version.Version
+	for _, v := range versions {
+		if !constraint.Check(v) {
+			continue
+		}
+		if res == nil || v.GreaterThan(res) {
+			res = v
+		}
+	}
+	return res
+}
+
+// Installed returns the compiler versions installed sorted in ascending order
+func (s *SolidityVersionManager) Installed() ([]*version.Version, error) {
+	entries, err := os.ReadDir(s.config.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*version.Version{}, nil
+		}
+		return nil, err
+	}
+
+	versions := []*version.Version{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasPrefix(entry.Name(), binaryPrefix) {
+			continue
+		}
+		v, err := version.NewVersion(strings.TrimPrefix(entry.Name(), binaryPrefix))
+		if err != nil {
+			// not a compiler binary
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Sort(version.Collection(versions))
+
+	return versions, nil
+}
+
+// Install downloads the compiler version if it is not installed yet
+func (s *SolidityVersionManager) Install(v *version.Version) error {
+	_, err := s.Resolve(v.String())
+	return err
+}
+
+// Remove deletes an installed compiler version
+func (s *SolidityVersionManager) Remove(v *version.Version) error {
+	if err := os.Remove(s.path(v.String())); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("solc %s: %w", v.String(), ErrVersionNotInstalled)
+		}
+		return err
+	}
+	return nil
+}
+
+// Prune removes all the installed compiler versions except the ones
+// in keep. It returns the list of versions removed.
+func (s *SolidityVersionManager) Prune(keep ...*version.Version) ([]*version.Version, error) {
+	installed, err := s.Installed()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []*version.Version{}
+	for _, v := range installed {
+		found := false
+		for _, k := range keep {
+			if v.Equal(k) {
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+		if err := s.Remove(v); err != nil {
+			return nil, err
+		}
+		removed = append(removed, v)
+	}
+	return removed, nil
+}
+
+// Releases returns the release manifest. The manifest is only fetched once
+// for the lifetime of the version manager.
+func (s *SolidityVersionManager) Releases() (*ReleaseList, error) {
+	d := &downloader{retries: s.config.retries, backoff: s.config.backoff}
+	return s.releases.get(context.Background(), d)
+}
+
+// installBinary writes the binary in the dst directory if it matches
+// the checksums of its build.
+func installBinary(ctx context.Context, bin *Binary, version string, dst string, progress ProgressFunc) error {
+	defer bin.Body.Close()
 
 	// check if the dst is correct
 	exists := false
@@ -118,13 +518,6 @@ func downloadSolidity(version string, dst string) error {
 
 	path := filepath.Join(tmpDir, name)
 
-	// Get the data
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
 	// Create the file
 	out, err := os.Create(path)
 	if err != nil {
@@ -132,11 +525,56 @@ func downloadSolidity(version string, dst string) error {
 	}
 	defer out.Close()
 
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
+	// Write the body to file and compute the checksums
+	sha256Hash := sha256.New()
+	keccakHash := sha3.NewLegacyKeccak256()
+
+	body := &progressReader{
+		ctx:      ctx,
+		r:        bin.Body,
+		version:  version,
+		total:    bin.Size,
+		progress: progress,
+	}
+	_, err = io.Copy(io.MultiWriter(out, sha256Hash, keccakHash), body)
 	if err != nil {
 		return err
 	}
+	// make sure the binary is fully written before it is visible
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// verify the checksums. The tmp folder is removed on failure
+	build := bin.Build
+	if build == nil {
+		build = &Build{}
+	}
+	checksums := []struct {
+		algorithm string
+		expected  string
+		hash      hash.Hash
+	}{
+		{"sha256", build.Sha256, sha256Hash},
+		{"keccak256", build.Keccak256, keccakHash},
+	}
+	for _, c := range checksums {
+		if c.expected == "" {
+			continue
+		}
+		actual := hex.EncodeToString(c.hash.Sum(nil))
+		if normalizeHash(c.expected) != actual {
+			return &ChecksumError{
+				Version:   version,
+				Algorithm: c.algorithm,
+				Expected:  normalizeHash(c.expected),
+				Actual:    actual,
+			}
+		}
+	}
 
 	// make binary executable
 	if err := os.Chmod(path, 0755); err != nil {
@@ -149,3 +587,25 @@ func downloadSolidity(version string, dst string) error {
 	}
 	return nil
 }
+
+// progressReader reports the bytes read and stops once the context is done
+type progressReader struct {
+	ctx      context.Context
+	r        io.Reader
+	version  string
+	received int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	p.received += int64(n)
+	if p.progress != nil && n != 0 {
+		p.progress(p.version, p.received, p.total)
+	}
+	return n, err
+}
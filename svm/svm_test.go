@@ -1,15 +1,448 @@
 package svm
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	version "github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
 )
 
+// testServer starts a file server that mirrors the layout of the
+// releases and the release list for the given versions
+func testServer(t *testing.T, versions ...string) *httptest.Server {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	list := &ReleaseList{
+		Releases: map[string]string{},
+	}
+	for _, version := range versions {
+		binary := fakeSolc(version)
+
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "v"+version), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "v"+version, "solc-static-linux"), binary, 0644))
+
+		versionList := testReleaseList(t, version, binary)
+		list.Builds = append(list.Builds, versionList.Builds...)
+		list.Releases[version] = versionList.Releases[version]
+		list.LatestRelease = version
+	}
+
+	data, err := json.Marshal(list)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "list.json"), data, 0644))
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
 func TestSVM(t *testing.T) {
-	svm, err := NewSolidityVersionManager()
+	srv := testServer(t, "0.8.0")
+
+	svm, err := NewSolidityVersionManager(WithDir(t.TempDir()), WithBaseURL(srv.URL), WithManifestURL(srv.URL))
+	require.NoError(t, err)
+
+	path, err := svm.Resolve("0.8.0")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, fakeSolc("0.8.0"), data)
+
+	_, err = svm.Resolve("0.8.1")
+	require.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestSVM_Offline(t *testing.T) {
+	srv := testServer(t, "0.8.0")
+	dir := t.TempDir()
+
+	// install the compiler while online
+	svm, err := NewSolidityVersionManager(WithDir(dir), WithBaseURL(srv.URL), WithManifestURL(srv.URL))
 	require.NoError(t, err)
 
 	_, err = svm.Resolve("0.8.0")
 	require.NoError(t, err)
+
+	srv.Close()
+
+	offline, err := NewSolidityVersionManager(WithDir(dir), WithOffline(true))
+	require.NoError(t, err)
+
+	_, err = offline.Resolve("0.8.0")
+	require.NoError(t, err)
+
+	_, err = offline.Resolve("0.8.1")
+	require.ErrorIs(t, err, ErrVersionNotInstalled)
+}
+
+// fakeSolc returns a script that reports the given compiler version
+func fakeSolc(version string) []byte {
+	return []byte("#!/bin/sh\necho 'solc, the solidity compiler commandline interface'\necho 'Version: " + version + "+commit.00000000.Linux.g++'\n")
+}
+
+func testReleaseList(t *testing.T, version string, binary []byte) *ReleaseList {
+	t.Helper()
+
+	sha256Hash := sha256.Sum256(binary)
+	keccakHash := sha3.NewLegacyKeccak256()
+	keccakHash.Write(binary)
+
+	path := "solc-linux-amd64-v" + version + "+commit.00000000"
+	return &ReleaseList{
+		Builds: []*Build{
+			{
+				Path:        path,
+				Version:     version,
+				Build:       "commit.00000000",
+				LongVersion: version + "+commit.00000000",
+				Sha256:      "0x" + hex.EncodeToString(sha256Hash[:]),
+				Keccak256:   "0x" + hex.EncodeToString(keccakHash.Sum(nil)),
+			},
+		},
+		Releases: map[string]string{
+			version: path,
+		},
+		LatestRelease: version,
+	}
+}
+
+func TestSVM_Releases(t *testing.T) {
+	list := testReleaseList(t, "0.8.0", []byte("binary"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/linux-amd64/list.json", r.URL.Path)
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer srv.Close()
+
+	svm, err := NewSolidityVersionManager(WithDir(t.TempDir()), WithManifestURL(srv.URL+"/linux-amd64"))
+	require.NoError(t, err)
+
+	releases, err := svm.Releases()
+	require.NoError(t, err)
+
+	build, ok := releases.Release("0.8.0")
+	require.True(t, ok)
+	require.Equal(t, list.Builds[0], build)
+
+	_, ok = releases.Release("0.8.1")
+	require.False(t, ok)
+}
+
+func TestSVM_InstallChecksum(t *testing.T) {
+	binary := []byte("binary")
+
+	newBinary := func(build *Build) *Binary {
+		return &Binary{
+			Body:  io.NopCloser(bytes.NewReader(binary)),
+			Build: build,
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		dir := t.TempDir()
+		build := testReleaseList(t, "0.8.0", binary).Builds[0]
+
+		require.NoError(t, installBinary(context.Background(), newBinary(build), "0.8.0", dir, nil))
+
+		data, err := os.ReadFile(filepath.Join(dir, "solidity-0.8.0"))
+		require.NoError(t, err)
+		require.Equal(t, binary, data)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		build := testReleaseList(t, "0.8.0", []byte("other binary")).Builds[0]
+
+		err := installBinary(context.Background(), newBinary(build), "0.8.0", dir, nil)
+
+		var checksumErr *ChecksumError
+		require.ErrorAs(t, err, &checksumErr)
+		require.Equal(t, "sha256", checksumErr.Algorithm)
+
+		// neither the binary nor the download folder are left behind
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+}
+
+func TestSVM_InstallRemovePrune(t *testing.T) {
+	srv := testServer(t, "0.8.0", "0.8.10", "0.8.2")
+
+	svm, err := NewSolidityVersionManager(WithDir(t.TempDir()), WithBaseURL(srv.URL), WithManifestURL(srv.URL))
+	require.NoError(t, err)
+
+	installed, err := svm.Installed()
+	require.NoError(t, err)
+	require.Empty(t, installed)
+
+	versions := []string{"0.8.10", "0.8.0", "0.8.2"}
+	for _, v := range versions {
+		require.NoError(t, svm.Install(version.Must(version.NewVersion(v))))
+	}
+
+	installedStr := func() []string {
+		installed, err := svm.Installed()
+		require.NoError(t, err)
+
+		res := []string{}
+		for _, v := range installed {
+			res = append(res, v.String())
+		}
+		return res
+	}
+	require.Equal(t, []string{"0.8.0", "0.8.2", "0.8.10"}, installedStr())
+
+	// remove a version
+	require.NoError(t, svm.Remove(version.Must(version.NewVersion("0.8.2"))))
+	require.Equal(t, []string{"0.8.0", "0.8.10"}, installedStr())
+
+	err = svm.Remove(version.Must(version.NewVersion("0.8.2")))
+	require.ErrorIs(t, err, ErrVersionNotInstalled)
+
+	// prune all except the latest version
+	removed, err := svm.Prune(version.Must(version.NewVersion("0.8.10")))
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	require.Equal(t, "0.8.0", removed[0].String())
+	require.Equal(t, []string{"0.8.10"}, installedStr())
+}
+
+func TestSVM_ResolveConstraint(t *testing.T) {
+	srv := testServer(t, "0.6.12", "0.8.0", "0.8.2", "0.8.10")
+	dir := t.TempDir()
+
+	svm, err := NewSolidityVersionManager(WithDir(dir), WithBaseURL(srv.URL), WithManifestURL(srv.URL))
+	require.NoError(t, err)
+
+	resolve := func(constraint string) (string, error) {
+		path, v, err := svm.ResolveConstraint(version.MustConstraints(version.NewConstraint(constraint)))
+		if err != nil {
+			return "", err
+		}
+		require.Equal(t, svm.path(v.String()), path)
+		return v.String(), nil
+	}
+
+	// nothing installed, pick the newest release
+	v, err := resolve(">= 0.8.0, < 0.9.0")
+	require.NoError(t, err)
+	require.Equal(t, "0.8.10", v)
+
+	v, err = resolve(">= 0.6.0, < 0.7.0")
+	require.NoError(t, err)
+	require.Equal(t, "0.6.12", v)
+
+	_, err = resolve(">= 0.9.0")
+	require.ErrorIs(t, err, ErrNoMatchingVersion)
+
+	// an installed version is preferred over a newer release
+	require.NoError(t, svm.Remove(version.Must(version.NewVersion("0.8.10"))))
+	require.NoError(t, svm.Install(version.Must(version.NewVersion("0.8.2"))))
+
+	v, err = resolve(">= 0.8.0, < 0.9.0")
+	require.NoError(t, err)
+	require.Equal(t, "0.8.2", v)
+
+	// offline mode only considers the installed versions
+	offline, err := NewSolidityVersionManager(WithDir(dir), WithOffline(true))
+	require.NoError(t, err)
+
+	_, _, err = offline.ResolveConstraint(version.MustConstraints(version.NewConstraint("= 0.8.0")))
+	require.ErrorIs(t, err, ErrVersionNotInstalled)
+}
+
+func TestSVM_ConcurrentResolve(t *testing.T) {
+	binary := fakeSolc("0.8.0")
+	list := testReleaseList(t, "0.8.0", binary)
+
+	var downloads int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/list.json" {
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+		atomic.AddInt64(&downloads, 1)
+
+		// slow down the download so that the calls overlap
+		time.Sleep(50 * time.Millisecond)
+		w.Write(binary)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	// two managers on the same directory simulate two different processes
+	managers := []*SolidityVersionManager{}
+	for i := 0; i < 2; i++ {
+		svm, err := NewSolidityVersionManager(WithDir(dir), WithBaseURL(srv.URL), WithManifestURL(srv.URL))
+		require.NoError(t, err)
+		managers = append(managers, svm)
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(svm *SolidityVersionManager) {
+			defer wg.Done()
+
+			path, err := svm.Resolve("0.8.0")
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			// the binary is fully written and executable
+			info, err := os.Stat(path)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if info.Size() != int64(len(binary)) || info.Mode()&0111 == 0 {
+				errCh <- fmt.Errorf("binary not installed correctly")
+			}
+		}(managers[i%2])
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int64(1), atomic.LoadInt64(&downloads))
+}
+
+func TestSVM_ResolveContext(t *testing.T) {
+	binary := fakeSolc("0.8.0")
+	list := testReleaseList(t, "0.8.0", binary)
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/list.json" {
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+		if r.URL.Path == "/v0.8.0/solc-static-linux" {
+			w.Write(binary)
+			return
+		}
+
+		// hang the download after writing the first bytes
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("solc"))
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-r.Context().Done():
+		case <-unblock:
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("progress", func(t *testing.T) {
+		var received, total int64
+
+		svm, err := NewSolidityVersionManager(WithDir(t.TempDir()), WithBaseURL(srv.URL), WithManifestURL(srv.URL), WithProgress(func(version string, r, tt int64) {
+			require.Equal(t, "0.8.0", version)
+			received, total = r, tt
+		}))
+		require.NoError(t, err)
+
+		_, err = svm.ResolveContext(context.Background(), "0.8.0")
+		require.NoError(t, err)
+
+		require.Equal(t, int64(len(binary)), received)
+		require.Equal(t, int64(len(binary)), total)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// the hung download is under a different version url
+		hungList := testReleaseList(t, "0.8.1", binary)
+		list.Builds = append(list.Builds, hungList.Builds...)
+		list.Releases["0.8.1"] = hungList.Releases["0.8.1"]
+
+		svm, err := NewSolidityVersionManager(WithDir(dir), WithBaseURL(srv.URL), WithManifestURL(srv.URL))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = svm.ResolveContext(ctx, "0.8.1")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		installed, err := svm.Installed()
+		require.NoError(t, err)
+		require.Empty(t, installed)
+	})
+
+	t.Run("cancel shared", func(t *testing.T) {
+		var requests int32
+		started := make(chan struct{}, 1)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/list.json" {
+				json.NewEncoder(w).Encode(list)
+				return
+			}
+			if atomic.AddInt32(&requests, 1) == 1 {
+				// hang the first download until its caller gives up
+				started <- struct{}{}
+				<-r.Context().Done()
+				return
+			}
+			w.Write(binary)
+		}))
+		defer srv.Close()
+
+		svm, err := NewSolidityVersionManager(WithDir(t.TempDir()), WithBaseURL(srv.URL), WithManifestURL(srv.URL), WithRetries(0, 0))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := svm.ResolveContext(ctx, "0.8.0")
+			errCh <- err
+		}()
+		<-started
+
+		// the second caller waits on the download of the first one
+		resCh := make(chan error, 1)
+		go func() {
+			_, err := svm.ResolveContext(context.Background(), "0.8.0")
+			resCh <- err
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		require.ErrorIs(t, <-errCh, context.Canceled)
+		require.NoError(t, <-resCh)
+	})
 }
@@ -0,0 +1,146 @@
+package svm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// VerificationError is returned when an installed binary is not a working
+// compiler of the expected version
+type VerificationError struct {
+	Version string
+	Path    string
+	Err     error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("invalid solc %s binary '%s': %v", e.Version, e.Path, e.Err)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// verifyResult is the cached result of the verification of a binary.
+// It is only valid while the binary is not modified.
+type verifyResult struct {
+	modTime time.Time
+	size    int64
+	err     error
+}
+
+// verify checks that the binary at path runs and reports the expected version.
+// The result is cached unless force is set.
+func (s *SolidityVersionManager) verify(ctx context.Context, ver string, path string, force bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	s.verifiedLock.Lock()
+	cached, ok := s.verified[path]
+	s.verifiedLock.Unlock()
+
+	if ok && !force && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.err
+	}
+
+	verifyErr := s.verifyBinary(ctx, ver, path)
+	if ctx.Err() != nil {
+		// do not cache the result of an aborted verification
+		return ctx.Err()
+	}
+
+	s.verifiedLock.Lock()
+	s.verified[path] = &verifyResult{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		err:     verifyErr,
+	}
+	s.verifiedLock.Unlock()
+
+	return verifyErr
+}
+
+func (s *SolidityVersionManager) verifyBinary(ctx context.Context, ver string, path string) error {
+	verErr := func(err error) error {
+		return &VerificationError{Version: ver, Path: path, Err: err}
+	}
+
+	expected, err := version.NewVersion(ver)
+	if err != nil {
+		return err
+	}
+
+	reported, commit, err := binaryVersion(ctx, path)
+	if err != nil {
+		return verErr(err)
+	}
+	if !reported.Equal(expected) {
+		return verErr(fmt.Errorf("binary reports version %s", reported.String()))
+	}
+
+	// check the commit if the release list is already available
+	if releases := s.releases.cached(); releases != nil && commit != "" {
+		if build, ok := releases.Release(ver); ok && build.Build != "commit."+commit {
+			return verErr(fmt.Errorf("binary reports commit %s but release is %s", commit, build.Build))
+		}
+	}
+	return nil
+}
+
+// InstallStatus is the status of an installed compiler reported by Doctor
+type InstallStatus struct {
+	// Version is the version of the compiler
+	Version *version.Version
+
+	// Path is the path of the binary
+	Path string
+
+	// Err is the verification error or nil if the binary is valid
+	Err error
+
+	// Reinstalled is true if the binary was broken and got reinstalled
+	Reinstalled bool
+
+	// ReinstallErr is the error of the reinstall if it failed. The broken
+	// binary is kept in that case.
+	ReinstallErr error
+}
+
+// Doctor verifies all the installed compilers and, if reinstall is set,
+// installs again the ones that are broken. The reinstall is skipped in offline mode.
+func (s *SolidityVersionManager) Doctor(reinstall bool) ([]*InstallStatus, error) {
+	ctx := context.Background()
+
+	installed, err := s.Installed()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*InstallStatus{}
+	for _, v := range installed {
+		status := &InstallStatus{
+			Version: v,
+			Path:    s.path(v.String()),
+		}
+		res = append(res, status)
+
+		status.Err = s.verify(ctx, v.String(), status.Path, true)
+		if status.Err == nil || !reinstall || s.config.offline {
+			continue
+		}
+
+		if err := s.reinstall(ctx, v.String()); err != nil {
+			status.ReinstallErr = err
+			continue
+		}
+		status.Err = nil
+		status.Reinstalled = true
+	}
+	return res, nil
+}
@@ -0,0 +1,114 @@
+package svm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSVM_Verify(t *testing.T) {
+	dir := t.TempDir()
+
+	svm, err := NewSolidityVersionManager(WithDir(dir), WithOffline(true))
+	require.NoError(t, err)
+
+	// the script logs each execution to count the verifications
+	logPath := filepath.Join(t.TempDir(), "log")
+	script := "#!/bin/sh\necho run >> " + logPath + "\necho 'Version: 0.8.0+commit.00000000.Linux.g++'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "solidity-0.8.0"), []byte(script), 0755))
+
+	for i := 0; i < 3; i++ {
+		_, err = svm.Resolve("0.8.0")
+		require.NoError(t, err)
+	}
+
+	// the result of the verification is cached
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "run"))
+
+	// a binary with a different version
+	writeFakeSolc(t, filepath.Join(dir, "solidity-0.8.1"), "0.8.2")
+
+	var verifyErr *VerificationError
+	_, err = svm.Resolve("0.8.1")
+	require.ErrorAs(t, err, &verifyErr)
+	require.Equal(t, "0.8.1", verifyErr.Version)
+
+	// a corrupted binary
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "solidity-0.8.2"), []byte{0x1, 0x2}, 0755))
+
+	_, err = svm.Resolve("0.8.2")
+	require.ErrorAs(t, err, &verifyErr)
+}
+
+func TestSVM_Doctor(t *testing.T) {
+	srv := testServer(t, "0.8.0", "0.8.1")
+	dir := t.TempDir()
+
+	svm, err := NewSolidityVersionManager(WithDir(dir), WithBaseURL(srv.URL), WithManifestURL(srv.URL))
+	require.NoError(t, err)
+
+	_, err = svm.Resolve("0.8.0")
+	require.NoError(t, err)
+
+	// corrupt the 0.8.1 binary
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "solidity-0.8.1"), []byte{0x1, 0x2}, 0755))
+
+	status, err := svm.Doctor(false)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+
+	require.NoError(t, status[0].Err)
+	require.Error(t, status[1].Err)
+	require.False(t, status[1].Reinstalled)
+
+	// reinstall the broken binary
+	status, err = svm.Doctor(true)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+
+	require.NoError(t, status[1].Err)
+	require.True(t, status[1].Reinstalled)
+
+	_, err = svm.Resolve("0.8.1")
+	require.NoError(t, err)
+}
+
+func TestSVM_DoctorReinstallFailure(t *testing.T) {
+	// the server does not have the 0.8.1 release anymore
+	srv := testServer(t, "0.8.0")
+	dir := t.TempDir()
+
+	broken := []byte{0x1, 0x2}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "solidity-0.8.1"), broken, 0755))
+
+	doctor := func(opts ...Option) *InstallStatus {
+		svm, err := NewSolidityVersionManager(append([]Option{WithDir(dir)}, opts...)...)
+		require.NoError(t, err)
+
+		status, err := svm.Doctor(true)
+		require.NoError(t, err)
+		require.Len(t, status, 1)
+
+		// the broken binary is kept
+		data, err := os.ReadFile(filepath.Join(dir, "solidity-0.8.1"))
+		require.NoError(t, err)
+		require.Equal(t, broken, data)
+
+		var verifyErr *VerificationError
+		require.ErrorAs(t, status[0].Err, &verifyErr)
+		require.False(t, status[0].Reinstalled)
+		return status[0]
+	}
+
+	// the reinstall is skipped in offline mode
+	status := doctor(WithOffline(true))
+	require.NoError(t, status.ReinstallErr)
+
+	status = doctor(WithBaseURL(srv.URL), WithManifestURL(srv.URL))
+	require.ErrorIs(t, status.ReinstallErr, ErrUnknownVersion)
+}